@@ -3,76 +3,342 @@ package argon2
 import (
 	"crypto/rand"
 	"crypto/subtle"
-	"encoding/base64"
 	"errors"
 	"fmt"
-	"strings"
+	"strconv"
 
 	"golang.org/x/crypto/argon2"
 )
 
 var ErrMismatchedHashAndPassword = errors.New("argon2: mismatched hash and password")
 
+// ErrInvalidParams is returned when the Params do not satisfy the RFC 9106 minimums.
+var ErrInvalidParams = errors.New("argon2: invalid params")
+
+// Variant is the Argon2 variant.
+type Variant int
+
+const (
+	// Argon2id is the hybrid variant recommended by RFC 9106. This is the zero value of the Variant.
+	Argon2id Variant = iota
+	// Argon2i is the data-independent variant.
+	Argon2i
+)
+
+// String returns the algorithm identifier of the PHC string format, e.g. argon2id.
+func (v Variant) String() string {
+	switch v {
+	case Argon2id:
+		return "argon2id"
+	case Argon2i:
+		return "argon2i"
+	default:
+		return fmt.Sprintf("Variant(%d)", int(v))
+	}
+}
+
+func parseVariant(id string) (Variant, error) {
+	switch id {
+	case "argon2id":
+		return Argon2id, nil
+	case "argon2i":
+		return Argon2i, nil
+	case "argon2d":
+		// golang.org/x/crypto/argon2 does not expose argon2d.
+		return 0, fmt.Errorf("%w: argon2d is not supported by golang.org/x/crypto/argon2", ErrUnsupportedAlgorithm)
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, id)
+	}
+}
+
+func (v Variant) key(password, salt []byte, p Params) []byte {
+	if v == Argon2i {
+		return argon2.Key(password, salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	}
+	return argon2.IDKey(password, salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+}
+
+// Params represents the Argon2 variant, cost parameters and the salt and key lengths.
+type Params struct {
+	// Variant is the Argon2 variant. The zero value is Argon2id.
+	Variant Variant
+	// Memory is the memory size in KiB.
+	Memory uint32
+	// Iterations is the number of passes over the memory.
+	Iterations uint32
+	// Parallelism is the number of lanes (threads).
+	Parallelism uint8
+	// SaltLength is the salt length in bytes.
+	SaltLength uint32
+	// KeyLength is the length of the generated key (tag) in bytes.
+	KeyLength uint32
+}
+
+// MaxMemory is the largest memory size in KiB accepted from an encoded hash,
+// so that a stored hash cannot force a huge allocation.
+// It is 2 GiB, the memory of the first recommended option of RFC 9106.
+const MaxMemory = 2 * 1024 * 1024
+
+// MaxIterations is the largest number of iterations accepted from an encoded hash,
+// so that a stored hash cannot force a huge computation.
+const MaxIterations = 32
+
+// DefaultParams is the RFC recommended Argon2id parameters.
+// https://datatracker.ietf.org/doc/html/rfc9106#section-4
+var DefaultParams = Params{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 4,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// Validate reports whether the p satisfies the minimums of RFC 9106 section 3.1.
+func (p Params) Validate() error {
+	if p.Variant != Argon2id && p.Variant != Argon2i {
+		return fmt.Errorf("%w: unknown variant %v", ErrInvalidParams, p.Variant)
+	}
+	if p.Parallelism < 1 {
+		return fmt.Errorf("%w: parallelism must be at least 1", ErrInvalidParams)
+	}
+	if p.Memory < 8*uint32(p.Parallelism) {
+		return fmt.Errorf("%w: memory must be at least 8*parallelism KiB", ErrInvalidParams)
+	}
+	// hashes over the limits could not be verified
+	if p.Memory > MaxMemory {
+		return fmt.Errorf("%w: memory must be at most %d KiB", ErrInvalidParams, MaxMemory)
+	}
+	if p.Iterations < 1 || p.Iterations > MaxIterations {
+		return fmt.Errorf("%w: iterations must be between 1 and %d", ErrInvalidParams, MaxIterations)
+	}
+	if p.SaltLength < 8 {
+		return fmt.Errorf("%w: salt length must be at least 8 bytes", ErrInvalidParams)
+	}
+	if p.KeyLength < 4 {
+		return fmt.Errorf("%w: key length must be at least 4 bytes", ErrInvalidParams)
+	}
+	return nil
+}
+
+// PHCPrefix returns the PHC string format of the p without salt and hash,
+// e.g. $argon2id$v=19$m=65536,t=3,p=4
+func (p Params) PHCPrefix() string {
+	h := decodedHash{version: argon2.Version, params: p}
+	return h.phc().String()
+}
+
+// GenerateFromPassword returns the encoded Argon2id hash of the password using the DefaultParams.
 func GenerateFromPassword(password string) (string, error) {
-	// RFC recommended Argon2id parameters
-	// https://datatracker.ietf.org/doc/html/rfc9106#section-4
-	const iterations = 3
-	const threads = 4
-	const memory = 64 * 1024
-	// salt and key length
-	const saltLen = 16
-	const keyLen = 32
+	return GenerateFromPasswordWithParams(password, DefaultParams)
+}
+
+// GenerateFromPasswordWithParams returns the encoded Argon2 hash of the password using the params.
+// The variant of the params is recorded as the algorithm identifier of the encoded hash.
+func GenerateFromPasswordWithParams(password string, params Params) (string, error) {
+	return generate([]byte(password), params, nil)
+}
+
+// DeriveKey derives a key of params.KeyLength bytes from the password and the salt, e.g. for encryption.
+// The params.SaltLength is ignored and the length of the salt is validated instead.
+func DeriveKey(password, salt []byte, params Params) ([]byte, error) {
+	params.SaltLength = uint32(len(salt))
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	return params.Variant.key(password, salt, params), nil
+}
+
+func generate(password []byte, params Params, keyID []byte) (string, error) {
+	if err := params.Validate(); err != nil {
+		return "", err
+	}
 	// generate salt
-	salt := make([]byte, saltLen)
+	salt := make([]byte, params.SaltLength)
 	if _, err := rand.Read(salt); err != nil {
 		return "", fmt.Errorf("argon2: generate salt %w", err)
 	}
 	// generate key
-	key := argon2.IDKey([]byte(password), salt, iterations, memory, threads, keyLen)
+	key := params.Variant.key(password, salt, params)
 	//　encode like https://github.com/P-H-C/phc-winner-argon2#command-line-utility
-	var b strings.Builder
-	b.WriteString("$argon2id")
-	b.WriteString(fmt.Sprintf("$v=%d", argon2.Version))
-	b.WriteString(fmt.Sprintf("$m=%d,t=%d,p=%d", memory, iterations, threads))
-	b.WriteString("$" + base64.RawStdEncoding.EncodeToString(salt))
-	b.WriteString("$" + base64.RawStdEncoding.EncodeToString(key))
-	return b.String(), nil
+	h := decodedHash{version: argon2.Version, params: params, keyID: keyID, salt: salt, key: key}
+	return h.phc().String(), nil
 }
 
+// CompareHashAndPassword compares the encoded Argon2id or Argon2i hash with the password.
+// It returns nil on success, or ErrMismatchedHashAndPassword on failure.
 func CompareHashAndPassword(encodedHash, password string) error {
-	// endocodedHash e.g
-	// $argon2id$v=19$m=65536,t=3,p=4$dDfMhYJIkUq8fzLMM7+tiw$AOWjpr5Psw3HxDMGSdPJdzEktQ/d3OIzZ/wuQWtUBvk
-	vals := strings.Split(encodedHash, "$")
-	if len(vals) != 6 {
-		return errors.New("argon2: unexpected hash format")
+	h, err := decodeHash(encodedHash)
+	if err != nil {
+		return err
 	}
-	if vals[1] != "argon2id" {
-		return fmt.Errorf("argon2: unsupported %s", vals[1])
+	if h.keyID != nil {
+		return fmt.Errorf("%w: %q (use CompareHashAndPasswordWithPepper)", ErrUnknownKeyID, h.keyID)
 	}
-	var version int
-	if _, err := fmt.Sscanf(vals[2], "v=%d", &version); err != nil {
-		return fmt.Errorf("argon2: scan version: %w", err)
+	return compare(h, []byte(password))
+}
+
+func compare(h *decodedHash, password []byte) error {
+	if h.version != argon2.Version {
+		return fmt.Errorf("%w: %d", ErrIncompatibleVersion, h.version)
 	}
-	if version != argon2.Version {
-		return fmt.Errorf("argon2: unsupported version %d", version)
+	if h.data != nil {
+		return fmt.Errorf("%w: associated data is not supported", ErrUnsupportedAlgorithm)
 	}
-	var iterations, memory uint32
-	var threads uint8
-	if _, err := fmt.Sscanf(vals[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil {
-		return fmt.Errorf("argon2: scan m t p: %w", err)
+	hash := h.params.Variant.key(password, h.salt, h.params)
+	if subtle.ConstantTimeCompare(h.key, hash) != 1 {
+		return ErrMismatchedHashAndPassword
 	}
-	salt, err := base64.RawStdEncoding.DecodeString(vals[4])
+	return nil
+}
+
+// NeedsRehash reports whether the encoded hash was generated with parameters
+// (variant, memory, iterations, parallelism, version or key length) that differ from the want.
+func NeedsRehash(encodedHash string, want Params) (bool, error) {
+	h, err := decodeHash(encodedHash)
 	if err != nil {
-		return fmt.Errorf("argon2: decode salt %s", vals[4])
+		return false, err
+	}
+	if h.version != argon2.Version {
+		return true, nil
+	}
+	got := h.params
+	if got.Variant != want.Variant ||
+		got.Memory != want.Memory ||
+		got.Iterations != want.Iterations ||
+		got.Parallelism != want.Parallelism ||
+		got.KeyLength != want.KeyLength {
+		return true, nil
 	}
-	key, err := base64.RawStdEncoding.DecodeString(vals[5])
+	return false, nil
+}
+
+// CompareAndUpgrade compares the encoded hash with the password like CompareHashAndPassword.
+// When the password matches and the hash needs rehash for the want, it returns a new encoded hash
+// generated with the want. Otherwise, newHash is empty.
+func CompareAndUpgrade(encodedHash, password string, want Params) (newHash string, err error) {
+	if err := CompareHashAndPassword(encodedHash, password); err != nil {
+		return "", err
+	}
+	needs, err := NeedsRehash(encodedHash, want)
 	if err != nil {
-		return fmt.Errorf("argon2: decode salt %s", vals[5])
+		return "", err
 	}
-	const keyLen = 32
-	hash := argon2.IDKey([]byte(password), salt, iterations, memory, threads, keyLen)
-	if subtle.ConstantTimeCompare(key, hash) != 1 {
-		return ErrMismatchedHashAndPassword
+	if !needs {
+		return "", nil
 	}
-	return nil
+	return GenerateFromPasswordWithParams(password, want)
+}
+
+// versionDefault is the version assumed when the encoded hash omits v=,
+// following the reference implementation.
+const versionDefault = 0x10
+
+type decodedHash struct {
+	version int
+	params  Params
+	keyID   []byte
+	data    []byte
+	salt    []byte
+	key     []byte
+}
+
+func decodeHash(encodedHash string) (*decodedHash, error) {
+	// endocodedHash e.g
+	// $argon2id$v=19$m=65536,t=3,p=4$dDfMhYJIkUq8fzLMM7+tiw$AOWjpr5Psw3HxDMGSdPJdzEktQ/d3OIzZ/wuQWtUBvk
+	phc, err := ParsePHC(encodedHash)
+	if err != nil {
+		return nil, err
+	}
+	variant, err := parseVariant(phc.ID)
+	if err != nil {
+		return nil, err
+	}
+	h := decodedHash{version: versionDefault}
+	h.params.Variant = variant
+	if phc.HasVersion {
+		h.version = phc.Version
+	}
+	var hasM, hasT, hasP bool
+	for _, p := range phc.Params {
+		switch p.Name {
+		case "m":
+			v, err := parseUint(p, 32)
+			if err != nil {
+				return nil, err
+			}
+			h.params.Memory, hasM = uint32(v), true
+		case "t":
+			v, err := parseUint(p, 32)
+			if err != nil {
+				return nil, err
+			}
+			h.params.Iterations, hasT = uint32(v), true
+		case "p":
+			v, err := parseUint(p, 8)
+			if err != nil {
+				return nil, err
+			}
+			h.params.Parallelism, hasP = uint8(v), true
+		case "keyid":
+			if h.keyID, err = b64.DecodeString(p.Value); err != nil {
+				return nil, fmt.Errorf("%w: decode keyid: %w", ErrInvalidFormat, err)
+			}
+		case "data":
+			if h.data, err = b64.DecodeString(p.Value); err != nil {
+				return nil, fmt.Errorf("%w: decode data: %w", ErrInvalidFormat, err)
+			}
+		default:
+			return nil, fmt.Errorf("%w: unknown param %q", ErrInvalidFormat, p.Name)
+		}
+	}
+	if !hasM || !hasT || !hasP {
+		return nil, fmt.Errorf("%w: m, t and p are required", ErrInvalidFormat)
+	}
+	// x/crypto/argon2 panics on t=0 and p=0
+	if h.params.Iterations < 1 || h.params.Parallelism < 1 {
+		return nil, fmt.Errorf("%w: t and p must be at least 1", ErrInvalidFormat)
+	}
+	if h.params.Memory < 8*uint32(h.params.Parallelism) || h.params.Memory > MaxMemory {
+		return nil, fmt.Errorf("%w: m must be between 8*p and %d", ErrInvalidFormat, MaxMemory)
+	}
+	if h.params.Iterations > MaxIterations {
+		return nil, fmt.Errorf("%w: t must be at most %d", ErrInvalidFormat, MaxIterations)
+	}
+	if len(phc.Salt) == 0 || len(phc.Hash) == 0 {
+		return nil, fmt.Errorf("%w: salt and hash are required", ErrInvalidFormat)
+	}
+	h.salt, h.key = phc.Salt, phc.Hash
+	h.params.SaltLength = uint32(len(h.salt))
+	h.params.KeyLength = uint32(len(h.key))
+	return &h, nil
+}
+
+func (h *decodedHash) phc() *PHCHash {
+	params := []PHCParam{
+		{Name: "m", Value: strconv.FormatUint(uint64(h.params.Memory), 10)},
+		{Name: "t", Value: strconv.FormatUint(uint64(h.params.Iterations), 10)},
+		{Name: "p", Value: strconv.FormatUint(uint64(h.params.Parallelism), 10)},
+	}
+	if h.keyID != nil {
+		params = append(params, PHCParam{Name: "keyid", Value: b64.EncodeToString(h.keyID)})
+	}
+	if h.data != nil {
+		params = append(params, PHCParam{Name: "data", Value: b64.EncodeToString(h.data)})
+	}
+	return &PHCHash{
+		ID:         h.params.Variant.String(),
+		Version:    h.version,
+		HasVersion: true,
+		Params:     params,
+		Salt:       h.salt,
+		Hash:       h.key,
+	}
+}
+
+func parseUint(p PHCParam, bitSize int) (uint64, error) {
+	v, err := parsePHCDecimal(p.Value)
+	if err != nil || v < 0 || uint64(v) >= 1<<bitSize {
+		return 0, fmt.Errorf("%w: invalid %s=%s", ErrInvalidFormat, p.Name, p.Value)
+	}
+	return uint64(v), nil
 }
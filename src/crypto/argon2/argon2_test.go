@@ -1,7 +1,9 @@
 package argon2
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -13,3 +15,167 @@ func TestGenerateFromPassword(t *testing.T) {
 	err = CompareHashAndPassword(g, "bar")
 	fmt.Println(err)
 }
+
+func TestGenerateFromPasswordWithParams(t *testing.T) {
+	params := Params{Memory: 8 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 8, KeyLength: 16}
+	g, err := GenerateFromPasswordWithParams("foo", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(g, "$argon2id$v=19$m=8192,t=1,p=1$") {
+		t.Errorf("unexpected hash %s", g)
+	}
+	if err := CompareHashAndPassword(g, "foo"); err != nil {
+		t.Errorf("err got %v, want nil", err)
+	}
+	if err := CompareHashAndPassword(g, "bar"); !errors.Is(err, ErrMismatchedHashAndPassword) {
+		t.Errorf("err got %v, want %v", err, ErrMismatchedHashAndPassword)
+	}
+}
+
+func TestParamsValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		params Params
+		ok     bool
+	}{
+		{name: "default", params: DefaultParams, ok: true},
+		{name: "minimum", params: Params{Memory: 8, Iterations: 1, Parallelism: 1, SaltLength: 8, KeyLength: 4}, ok: true},
+		{name: "zero parallelism", params: Params{Memory: 8, Iterations: 1, Parallelism: 0, SaltLength: 8, KeyLength: 4}},
+		{name: "memory less than 8*p", params: Params{Memory: 31, Iterations: 1, Parallelism: 4, SaltLength: 8, KeyLength: 4}},
+		{name: "zero iterations", params: Params{Memory: 8, Iterations: 0, Parallelism: 1, SaltLength: 8, KeyLength: 4}},
+		{name: "too many iterations", params: Params{Memory: 8, Iterations: MaxIterations + 1, Parallelism: 1, SaltLength: 8, KeyLength: 4}},
+		{name: "too much memory", params: Params{Memory: MaxMemory + 1, Iterations: 1, Parallelism: 1, SaltLength: 8, KeyLength: 4}},
+		{name: "short salt", params: Params{Memory: 8, Iterations: 1, Parallelism: 1, SaltLength: 7, KeyLength: 4}},
+		{name: "short key", params: Params{Memory: 8, Iterations: 1, Parallelism: 1, SaltLength: 8, KeyLength: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if tt.ok && err != nil {
+				t.Errorf("err got %v, want nil", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidParams) {
+				t.Errorf("err got %v, want %v", err, ErrInvalidParams)
+			}
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	old := Params{Memory: 8 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	g, err := GenerateFromPasswordWithParams("foo", old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		want Params
+		need bool
+	}{
+		{name: "same", want: old, need: false},
+		{name: "salt length only", want: Params{Memory: 8 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 32, KeyLength: 32}, need: false},
+		{name: "memory", want: Params{Memory: 16 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}, need: true},
+		{name: "iterations", want: Params{Memory: 8 * 1024, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}, need: true},
+		{name: "parallelism", want: Params{Memory: 8 * 1024, Iterations: 1, Parallelism: 2, SaltLength: 16, KeyLength: 32}, need: true},
+		{name: "key length", want: Params{Memory: 8 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 64}, need: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			need, err := NeedsRehash(g, tt.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if need != tt.need {
+				t.Errorf("need got %v, want %v", need, tt.need)
+			}
+		})
+	}
+
+	t.Run("old version", func(t *testing.T) {
+		v16 := strings.Replace(g, "$v=19$", "$v=16$", 1)
+		need, err := NeedsRehash(v16, old)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !need {
+			t.Errorf("need got false, want true")
+		}
+	})
+}
+
+func TestCompareAndUpgrade(t *testing.T) {
+	old := Params{Memory: 8 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	want := Params{Memory: 16 * 1024, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	g, err := GenerateFromPasswordWithParams("foo", old)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := CompareAndUpgrade(g, "bar", want); !errors.Is(err, ErrMismatchedHashAndPassword) {
+		t.Errorf("err got %v, want %v", err, ErrMismatchedHashAndPassword)
+	}
+
+	upgraded, err := CompareAndUpgrade(g, "foo", want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(upgraded, "$argon2id$v=19$m=16384,t=2,p=1$") {
+		t.Errorf("unexpected upgraded hash %s", upgraded)
+	}
+	if err := CompareHashAndPassword(upgraded, "foo"); err != nil {
+		t.Errorf("err got %v, want nil", err)
+	}
+
+	same, err := CompareAndUpgrade(upgraded, "foo", want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same != "" {
+		t.Errorf("newHash got %s, want empty", same)
+	}
+}
+
+func TestVariants(t *testing.T) {
+	t.Run("argon2i reference", func(t *testing.T) {
+		// generated by the reference implementation:
+		// echo -n password | argon2 somesalt -t 2 -m 16 -p 4 -l 24
+		const encoded = "$argon2i$v=19$m=65536,t=2,p=4$c29tZXNhbHQ$RdescudvJCsgt3ub+b+dWRWJTmaaJObG"
+		if err := CompareHashAndPassword(encoded, "password"); err != nil {
+			t.Errorf("err got %v, want nil", err)
+		}
+		if err := CompareHashAndPassword(encoded, "bar"); !errors.Is(err, ErrMismatchedHashAndPassword) {
+			t.Errorf("err got %v, want %v", err, ErrMismatchedHashAndPassword)
+		}
+	})
+
+	t.Run("argon2i round trip", func(t *testing.T) {
+		params := Params{Variant: Argon2i, Memory: 8 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+		g, err := GenerateFromPasswordWithParams("foo", params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(g, "$argon2i$v=19$m=8192,t=1,p=1$") {
+			t.Errorf("unexpected hash %s", g)
+		}
+		if err := CompareHashAndPassword(g, "foo"); err != nil {
+			t.Errorf("err got %v, want nil", err)
+		}
+		// migrating from argon2i to argon2id
+		params.Variant = Argon2id
+		upgraded, err := CompareAndUpgrade(g, "foo", params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(upgraded, "$argon2id$v=19$m=8192,t=1,p=1$") {
+			t.Errorf("unexpected upgraded hash %s", upgraded)
+		}
+	})
+
+	t.Run("argon2d", func(t *testing.T) {
+		const encoded = "$argon2d$v=19$m=65536,t=2,p=4$c29tZXNhbHQ$RdescudvJCsgt3ub+b+dWRWJTmaaJObG"
+		if err := CompareHashAndPassword(encoded, "password"); !errors.Is(err, ErrUnsupportedAlgorithm) {
+			t.Errorf("err got %v, want %v", err, ErrUnsupportedAlgorithm)
+		}
+	})
+}
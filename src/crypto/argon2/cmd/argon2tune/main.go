@@ -0,0 +1,60 @@
+// Command argon2tune benchmarks Argon2 on the current machine and recommends
+// the strongest parameters that fit the target latency and the memory ceiling.
+//
+//	go run ./src/crypto/argon2/cmd/argon2tune -target 500ms -max-memory 262144
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/kei2100/playground-go/src/crypto/argon2"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func run() error {
+	target := flag.Duration("target", 500*time.Millisecond, "maximum duration of a single hashing")
+	maxMemory := flag.Uint("max-memory", 256*1024, "memory ceiling in KiB")
+	parallelism := flag.Uint("p", 0, "number of lanes (0 means the number of CPUs)")
+	variant := flag.String("variant", "argon2id", "argon2id or argon2i")
+	flag.Parse()
+	if *maxMemory > 1<<32-1 || *parallelism > 255 {
+		return fmt.Errorf("max-memory or p out of range")
+	}
+
+	opts := argon2.TuneOptions{
+		Target:      *target,
+		MaxMemory:   uint32(*maxMemory),
+		Parallelism: uint8(*parallelism),
+	}
+	switch *variant {
+	case "argon2id":
+		opts.Variant = argon2.Argon2id
+	case "argon2i":
+		opts.Variant = argon2.Argon2i
+	default:
+		return fmt.Errorf("unknown variant %s", *variant)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	p, err := argon2.Tune(ctx, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%+v\n", p)
+	fmt.Println(p.PHCPrefix())
+	return nil
+}
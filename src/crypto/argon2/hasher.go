@@ -0,0 +1,126 @@
+package argon2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// ErrMemoryBudgetExceeded is returned when a single hashing needs more memory than the Hasher budget.
+var ErrMemoryBudgetExceeded = errors.New("argon2: memory budget exceeded")
+
+// Hasher limits the concurrent hashing so that the total memory cost stays within the budget.
+// Each hashing acquires the weight of its memory cost (KiB) from a weighted semaphore,
+// and the callers wait in the queue until enough memory is released or the context is done.
+type Hasher struct {
+	params Params
+	budget uint32
+	sem    *semaphore.Weighted
+
+	waiting  atomic.Int64
+	running  atomic.Int64
+	acquired atomic.Int64
+	waitTime atomic.Int64 // nanoseconds
+	maxWait  atomic.Int64 // nanoseconds
+}
+
+// HasherStats is a snapshot of the Hasher statistics.
+type HasherStats struct {
+	// Waiting is the number of callers waiting in the queue.
+	Waiting int64
+	// Running is the number of hashings in progress.
+	Running int64
+	// Acquired is the total number of hashings that acquired the memory.
+	Acquired int64
+	// TotalWaitTime is the total time the acquired hashings waited in the queue.
+	TotalWaitTime time.Duration
+	// MaxWaitTime is the longest time a hashing waited in the queue.
+	MaxWaitTime time.Duration
+}
+
+// NewHasher returns a Hasher generating hashes with the params
+// and using at most memoryBudget KiB at once.
+func NewHasher(params Params, memoryBudget uint32) (*Hasher, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	if memoryBudget < params.Memory {
+		return nil, fmt.Errorf("%w: budget %d KiB is less than memory %d KiB", ErrMemoryBudgetExceeded, memoryBudget, params.Memory)
+	}
+	return &Hasher{
+		params: params,
+		budget: memoryBudget,
+		sem:    semaphore.NewWeighted(int64(memoryBudget)),
+	}, nil
+}
+
+// GenerateFromPassword is like GenerateFromPasswordWithParams with the Hasher params,
+// but waits until the memory is available or the ctx is done.
+func (h *Hasher) GenerateFromPassword(ctx context.Context, password string) (string, error) {
+	release, err := h.acquire(ctx, h.params.Memory)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return GenerateFromPasswordWithParams(password, h.params)
+}
+
+// CompareHashAndPassword is like the package function CompareHashAndPassword,
+// but waits until the memory of the encoded hash is available or the ctx is done.
+func (h *Hasher) CompareHashAndPassword(ctx context.Context, encodedHash, password string) error {
+	d, err := decodeHash(encodedHash)
+	if err != nil {
+		return err
+	}
+	if d.keyID != nil {
+		return fmt.Errorf("%w: %q", ErrUnknownKeyID, d.keyID)
+	}
+	release, err := h.acquire(ctx, d.params.Memory)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return compare(d, []byte(password))
+}
+
+// Stats returns the current statistics.
+func (h *Hasher) Stats() HasherStats {
+	return HasherStats{
+		Waiting:       h.waiting.Load(),
+		Running:       h.running.Load(),
+		Acquired:      h.acquired.Load(),
+		TotalWaitTime: time.Duration(h.waitTime.Load()),
+		MaxWaitTime:   time.Duration(h.maxWait.Load()),
+	}
+}
+
+func (h *Hasher) acquire(ctx context.Context, memory uint32) (release func(), err error) {
+	if memory > h.budget {
+		return nil, fmt.Errorf("%w: memory %d KiB is more than budget %d KiB", ErrMemoryBudgetExceeded, memory, h.budget)
+	}
+	start := time.Now()
+	h.waiting.Add(1)
+	err = h.sem.Acquire(ctx, int64(memory))
+	h.waiting.Add(-1)
+	if err != nil {
+		return nil, fmt.Errorf("argon2: acquire memory: %w", err)
+	}
+	wait := int64(time.Since(start))
+	h.acquired.Add(1)
+	h.waitTime.Add(wait)
+	for {
+		cur := h.maxWait.Load()
+		if wait <= cur || h.maxWait.CompareAndSwap(cur, wait) {
+			break
+		}
+	}
+	h.running.Add(1)
+	return func() {
+		h.running.Add(-1)
+		h.sem.Release(int64(memory))
+	}, nil
+}
@@ -0,0 +1,84 @@
+package argon2
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHasher(t *testing.T) {
+	params := Params{Memory: 8 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	h, err := NewHasher(params, 16*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			g, err := h.GenerateFromPassword(ctx, "foo")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if err := h.CompareHashAndPassword(ctx, g, "foo"); err != nil {
+				t.Error(err)
+			}
+			if err := h.CompareHashAndPassword(ctx, g, "bar"); !errors.Is(err, ErrMismatchedHashAndPassword) {
+				t.Errorf("err got %v, want %v", err, ErrMismatchedHashAndPassword)
+			}
+		})
+	}
+	wg.Wait()
+
+	s := h.Stats()
+	if s.Acquired != 24 {
+		t.Errorf("acquired got %v, want 24", s.Acquired)
+	}
+	if s.Waiting != 0 || s.Running != 0 {
+		t.Errorf("waiting and running got %v, %v, want 0", s.Waiting, s.Running)
+	}
+}
+
+func TestHasherContextCanceled(t *testing.T) {
+	params := Params{Memory: 8 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	h, err := NewHasher(params, 8*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// occupy the whole budget
+	release, err := h.acquire(context.Background(), params.Memory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := h.GenerateFromPassword(ctx, "foo"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err got %v, want %v", err, context.DeadlineExceeded)
+	}
+	if s := h.Stats(); s.Waiting != 0 || s.Running != 1 {
+		t.Errorf("waiting and running got %v, %v, want 0, 1", s.Waiting, s.Running)
+	}
+}
+
+func TestHasherMemoryBudgetExceeded(t *testing.T) {
+	params := Params{Memory: 8 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	if _, err := NewHasher(params, 4*1024); !errors.Is(err, ErrMemoryBudgetExceeded) {
+		t.Errorf("err got %v, want %v", err, ErrMemoryBudgetExceeded)
+	}
+
+	h, err := NewHasher(params, 8*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// m=65536 needs more than the budget
+	const encoded = "$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"
+	if err := h.CompareHashAndPassword(context.Background(), encoded, "password"); !errors.Is(err, ErrMemoryBudgetExceeded) {
+		t.Errorf("err got %v, want %v", err, ErrMemoryBudgetExceeded)
+	}
+}
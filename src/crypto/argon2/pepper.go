@@ -0,0 +1,108 @@
+package argon2
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownKeyID is returned when the pepper of the keyid recorded in the encoded hash is not found.
+var ErrUnknownKeyID = errors.New("argon2: unknown keyid")
+
+// ErrUnpeppered is returned when the encoded hash has no keyid, i.e. was generated without a pepper,
+// and the keyring does not accept such hashes (see Keyring.WithUnpeppered).
+var ErrUnpeppered = errors.New("argon2: hash without pepper")
+
+// maxKeyIDLength is the maximum length of the keyid of the Argon2 PHC string format.
+const maxKeyIDLength = 8
+
+// Keyring holds the server-side secrets (peppers) by the key id.
+// The active pepper is used for generating new hashes,
+// and all the peppers are used for verifying the hashes.
+type Keyring struct {
+	activeID   string
+	peppers    map[string][]byte
+	unpeppered bool
+}
+
+// NewKeyring returns a Keyring. The activeID must be one of the keys of the peppers.
+// The key ids are recorded as the keyid of the encoded hashes, so they must be 1 to 8 bytes.
+func NewKeyring(activeID string, peppers map[string][]byte) (*Keyring, error) {
+	for id, pepper := range peppers {
+		if len(id) < 1 || maxKeyIDLength < len(id) {
+			return nil, fmt.Errorf("argon2: key id %q must be 1 to %d bytes", id, maxKeyIDLength)
+		}
+		if len(pepper) == 0 {
+			return nil, fmt.Errorf("argon2: empty pepper of key id %q", id)
+		}
+	}
+	if _, ok := peppers[activeID]; !ok {
+		return nil, fmt.Errorf("%w: active key id %q", ErrUnknownKeyID, activeID)
+	}
+	k := &Keyring{activeID: activeID, peppers: make(map[string][]byte, len(peppers))}
+	for id, pepper := range peppers {
+		k.peppers[id] = append([]byte(nil), pepper...)
+	}
+	return k, nil
+}
+
+// WithUnpeppered returns a copy of the k that also accepts hashes without keyid, which are compared
+// without the pepper. It is meant for migrating the hashes generated before introducing the pepper:
+// NeedsRotation reports true for such hashes, so rehash them with GenerateFromPasswordWithPepper
+// on successful login, and stop using WithUnpeppered once all the hashes are peppered.
+func (k *Keyring) WithUnpeppered() *Keyring {
+	k2 := *k
+	k2.unpeppered = true
+	return &k2
+}
+
+// NeedsRotation reports whether the encoded hash was not generated with the active pepper.
+// It reports true for a hash without keyid as well.
+func (k *Keyring) NeedsRotation(encodedHash string) (bool, error) {
+	h, err := decodeHash(encodedHash)
+	if err != nil {
+		return false, err
+	}
+	return string(h.keyID) != k.activeID, nil
+}
+
+// GenerateFromPasswordWithPepper returns the encoded Argon2 hash of the password
+// mixed with the active pepper of the keyring. The key id of the pepper is recorded as the keyid.
+//
+// golang.org/x/crypto/argon2 does not expose the secret input (K) of Argon2,
+// so the pepper is mixed in as HMAC-SHA256(pepper, password) before hashing.
+func GenerateFromPasswordWithPepper(password string, params Params, keyring *Keyring) (string, error) {
+	pepper := keyring.peppers[keyring.activeID]
+	return generate(peppered(pepper, password), params, []byte(keyring.activeID))
+}
+
+// CompareHashAndPasswordWithPepper compares the encoded hash with the password
+// mixed with the pepper selected from the keyring by the keyid of the hash.
+// It returns ErrUnknownKeyID if the keyring does not have the pepper.
+// A hash without keyid is rejected with ErrUnpeppered, unless the keyring is created by Keyring.WithUnpeppered,
+// so that a hash written without the pepper (e.g. by an attacker with write access to the database)
+// is not silently accepted.
+func CompareHashAndPasswordWithPepper(encodedHash, password string, keyring *Keyring) error {
+	h, err := decodeHash(encodedHash)
+	if err != nil {
+		return err
+	}
+	if h.keyID == nil {
+		if !keyring.unpeppered {
+			return ErrUnpeppered
+		}
+		return compare(h, []byte(password))
+	}
+	pepper, ok := keyring.peppers[string(h.keyID)]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownKeyID, h.keyID)
+	}
+	return compare(h, peppered(pepper, password))
+}
+
+func peppered(pepper []byte, password string) []byte {
+	mac := hmac.New(sha256.New, pepper)
+	mac.Write([]byte(password))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,112 @@
+package argon2
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPepper(t *testing.T) {
+	params := Params{Memory: 8 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	k1, err := NewKeyring("k1", map[string][]byte{"k1": []byte("pepper1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := GenerateFromPasswordWithPepper("foo", params, k1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// keyid=base64("k1")
+	if !strings.HasPrefix(g, "$argon2id$v=19$m=8192,t=1,p=1,keyid=azE$") {
+		t.Errorf("unexpected hash %s", g)
+	}
+	if err := CompareHashAndPasswordWithPepper(g, "foo", k1); err != nil {
+		t.Errorf("err got %v, want nil", err)
+	}
+	if err := CompareHashAndPasswordWithPepper(g, "bar", k1); !errors.Is(err, ErrMismatchedHashAndPassword) {
+		t.Errorf("err got %v, want %v", err, ErrMismatchedHashAndPassword)
+	}
+	if err := CompareHashAndPassword(g, "foo"); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("err got %v, want %v", err, ErrUnknownKeyID)
+	}
+
+	t.Run("rotation", func(t *testing.T) {
+		k2, err := NewKeyring("k2", map[string][]byte{"k1": []byte("pepper1"), "k2": []byte("pepper2")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// hashes of the retained pepper are still verified
+		if err := CompareHashAndPasswordWithPepper(g, "foo", k2); err != nil {
+			t.Errorf("err got %v, want nil", err)
+		}
+		needs, err := k2.NeedsRotation(g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !needs {
+			t.Errorf("needs rotation got false, want true")
+		}
+		g2, err := GenerateFromPasswordWithPepper("foo", params, k2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if needs, _ := k2.NeedsRotation(g2); needs {
+			t.Errorf("needs rotation got true, want false")
+		}
+		// a different pepper must not verify
+		wrong, err := NewKeyring("k2", map[string][]byte{"k2": []byte("wrong")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := CompareHashAndPasswordWithPepper(g2, "foo", wrong); !errors.Is(err, ErrMismatchedHashAndPassword) {
+			t.Errorf("err got %v, want %v", err, ErrMismatchedHashAndPassword)
+		}
+	})
+
+	t.Run("unknown keyid", func(t *testing.T) {
+		k3, err := NewKeyring("k3", map[string][]byte{"k3": []byte("pepper3")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := CompareHashAndPasswordWithPepper(g, "foo", k3); !errors.Is(err, ErrUnknownKeyID) {
+			t.Errorf("err got %v, want %v", err, ErrUnknownKeyID)
+		}
+	})
+
+	t.Run("without keyid", func(t *testing.T) {
+		plain, err := GenerateFromPasswordWithParams("foo", params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := CompareHashAndPasswordWithPepper(plain, "foo", k1); !errors.Is(err, ErrUnpeppered) {
+			t.Errorf("err got %v, want %v", err, ErrUnpeppered)
+		}
+		// opt-in for migration
+		migrating := k1.WithUnpeppered()
+		if err := CompareHashAndPasswordWithPepper(plain, "foo", migrating); err != nil {
+			t.Errorf("err got %v, want nil", err)
+		}
+		if err := CompareHashAndPasswordWithPepper(plain, "bar", migrating); !errors.Is(err, ErrMismatchedHashAndPassword) {
+			t.Errorf("err got %v, want %v", err, ErrMismatchedHashAndPassword)
+		}
+		// the original keyring is not changed
+		if err := CompareHashAndPasswordWithPepper(plain, "foo", k1); !errors.Is(err, ErrUnpeppered) {
+			t.Errorf("err got %v, want %v", err, ErrUnpeppered)
+		}
+		if needs, _ := migrating.NeedsRotation(plain); !needs {
+			t.Errorf("needs rotation got false, want true")
+		}
+	})
+}
+
+func TestNewKeyring(t *testing.T) {
+	if _, err := NewKeyring("k0", map[string][]byte{"k1": []byte("p")}); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("err got %v, want %v", err, ErrUnknownKeyID)
+	}
+	if _, err := NewKeyring("toolongid", map[string][]byte{"toolongid": []byte("p")}); err == nil {
+		t.Errorf("err got nil, want error")
+	}
+	if _, err := NewKeyring("k1", map[string][]byte{"k1": nil}); err == nil {
+		t.Errorf("err got nil, want error")
+	}
+}
@@ -0,0 +1,202 @@
+package argon2
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidFormat is returned when the encoded hash does not follow the PHC string format.
+	ErrInvalidFormat = errors.New("argon2: invalid format")
+	// ErrUnsupportedAlgorithm is returned when the algorithm identifier of the encoded hash is not supported.
+	ErrUnsupportedAlgorithm = errors.New("argon2: unsupported algorithm")
+	// ErrIncompatibleVersion is returned when the version of the encoded hash is not supported.
+	ErrIncompatibleVersion = errors.New("argon2: incompatible version")
+)
+
+// b64 is the B64 encoding of the PHC string format (standard base64 without padding).
+var b64 = base64.RawStdEncoding.Strict()
+
+// PHCHash represents a hash in the PHC string format.
+//
+//	$<id>[$v=<version>][$<param>=<value>(,<param>=<value>)*][$<salt>[$<hash>]]
+//
+// https://github.com/P-H-C/phc-string-format/blob/master/phc-sf-spec.md
+type PHCHash struct {
+	// ID is the symbolic name of the function, e.g. argon2id.
+	ID string
+	// Version is the version of the function. It is meaningful only if HasVersion is true.
+	Version    int
+	HasVersion bool
+	// Params is the ordered list of the function parameters.
+	Params []PHCParam
+	// Salt is the decoded salt. Nil means the salt is omitted.
+	Salt []byte
+	// Hash is the decoded hash output. Nil means the hash is omitted.
+	Hash []byte
+}
+
+// PHCParam is a name=value pair of the PHCHash parameters.
+type PHCParam struct {
+	Name  string
+	Value string
+}
+
+// Param returns the value of the parameter named name.
+func (h *PHCHash) Param(name string) (string, bool) {
+	for _, p := range h.Params {
+		if p.Name == name {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// ParsePHC parses the s as the PHC string format.
+func ParsePHC(s string) (*PHCHash, error) {
+	if !strings.HasPrefix(s, "$") {
+		return nil, fmt.Errorf("%w: must start with $", ErrInvalidFormat)
+	}
+	fields := strings.Split(s[1:], "$")
+
+	var h PHCHash
+	h.ID, fields = fields[0], fields[1:]
+	if !isPHCName(h.ID) {
+		return nil, fmt.Errorf("%w: invalid id %q", ErrInvalidFormat, h.ID)
+	}
+	// version
+	if len(fields) > 0 && strings.HasPrefix(fields[0], "v=") {
+		v, err := parsePHCDecimal(fields[0][len("v="):])
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("%w: invalid version %q", ErrInvalidFormat, fields[0])
+		}
+		h.Version, h.HasVersion = int(v), true
+		fields = fields[1:]
+	}
+	// params. B64 alphabet does not contain '=', so a field having '=' is not a salt.
+	if len(fields) > 0 && strings.Contains(fields[0], "=") {
+		params, err := parsePHCParams(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		h.Params = params
+		fields = fields[1:]
+	}
+	// salt
+	if len(fields) > 0 {
+		salt, err := b64.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%w: decode salt: %w", ErrInvalidFormat, err)
+		}
+		h.Salt = salt
+		fields = fields[1:]
+	}
+	// hash
+	if len(fields) > 0 {
+		if fields[0] == "" {
+			return nil, fmt.Errorf("%w: empty hash", ErrInvalidFormat)
+		}
+		hash, err := b64.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%w: decode hash: %w", ErrInvalidFormat, err)
+		}
+		h.Hash = hash
+		fields = fields[1:]
+	}
+	if len(fields) > 0 {
+		return nil, fmt.Errorf("%w: unexpected trailing fields", ErrInvalidFormat)
+	}
+	return &h, nil
+}
+
+// String returns the PHC string format of the h.
+func (h *PHCHash) String() string {
+	var b strings.Builder
+	b.WriteString("$" + h.ID)
+	if h.HasVersion {
+		b.WriteString("$v=" + strconv.Itoa(h.Version))
+	}
+	if len(h.Params) > 0 {
+		b.WriteString("$")
+		for i, p := range h.Params {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString(p.Name + "=" + p.Value)
+		}
+	}
+	if h.Salt != nil || h.Hash != nil {
+		b.WriteString("$" + b64.EncodeToString(h.Salt))
+	}
+	if h.Hash != nil {
+		b.WriteString("$" + b64.EncodeToString(h.Hash))
+	}
+	return b.String()
+}
+
+func parsePHCParams(s string) ([]PHCParam, error) {
+	var params []PHCParam
+	seen := make(map[string]bool)
+	for kv := range strings.SplitSeq(s, ",") {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: invalid param %q", ErrInvalidFormat, kv)
+		}
+		if !isPHCName(name) || name == "v" {
+			return nil, fmt.Errorf("%w: invalid param name %q", ErrInvalidFormat, name)
+		}
+		if !isPHCValue(value) {
+			return nil, fmt.Errorf("%w: invalid param value %q", ErrInvalidFormat, kv)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: duplicate param %q", ErrInvalidFormat, name)
+		}
+		seen[name] = true
+		params = append(params, PHCParam{Name: name, Value: value})
+	}
+	return params, nil
+}
+
+// parsePHCDecimal parses the s as a decimal integer of the PHC string format,
+// which must not have a leading zero (except 0 itself) nor a leading '+'.
+func parsePHCDecimal(s string) (int64, error) {
+	digits := strings.TrimPrefix(s, "-")
+	if digits == "" || (len(digits) > 1 && digits[0] == '0') || (s[0] == '-' && digits == "0") {
+		return 0, fmt.Errorf("%w: invalid decimal %q", ErrInvalidFormat, s)
+	}
+	for _, c := range digits {
+		if c < '0' || '9' < c {
+			return 0, fmt.Errorf("%w: invalid decimal %q", ErrInvalidFormat, s)
+		}
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// isPHCName reports whether the s matches [a-z0-9-]{1,32}.
+func isPHCName(s string) bool {
+	if len(s) < 1 || 32 < len(s) {
+		return false
+	}
+	for _, c := range s {
+		if !('a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// isPHCValue reports whether the s matches [a-zA-Z0-9/+.-]+.
+func isPHCValue(s string) bool {
+	if len(s) < 1 {
+		return false
+	}
+	for _, c := range s {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '/' || c == '+' || c == '.' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
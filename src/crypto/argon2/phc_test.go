@@ -0,0 +1,136 @@
+package argon2
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParsePHC(t *testing.T) {
+	tests := []struct {
+		in      string
+		id      string
+		version int
+		params  int
+		salt    bool
+		hash    bool
+	}{
+		{in: "$argon2id", id: "argon2id", version: -1},
+		{in: "$argon2id$v=19", id: "argon2id", version: 19},
+		{in: "$argon2id$v=19$m=65536,t=3,p=4", id: "argon2id", version: 19, params: 3},
+		{in: "$argon2id$m=65536,t=3,p=4$c2FsdHNhbHQ", id: "argon2id", version: -1, params: 3, salt: true},
+		{in: "$argon2id$v=19$t=3,p=4,m=65536,keyid=AAEC,data=AwQ$c2FsdHNhbHQ$aGFzaA", id: "argon2id", version: 19, params: 5, salt: true, hash: true},
+		{in: "$pbkdf2-sha256$i=10000$c2FsdHNhbHQ$aGFzaA", id: "pbkdf2-sha256", version: -1, params: 1, salt: true, hash: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			h, err := ParsePHC(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h.ID != tt.id {
+				t.Errorf("id got %v, want %v", h.ID, tt.id)
+			}
+			if tt.version < 0 && h.HasVersion {
+				t.Errorf("unexpected version %v", h.Version)
+			}
+			if tt.version >= 0 && (!h.HasVersion || h.Version != tt.version) {
+				t.Errorf("version got %v, want %v", h.Version, tt.version)
+			}
+			if len(h.Params) != tt.params {
+				t.Errorf("params got %v, want %v", h.Params, tt.params)
+			}
+			if (h.Salt != nil) != tt.salt {
+				t.Errorf("salt got %v, want present=%v", h.Salt, tt.salt)
+			}
+			if (h.Hash != nil) != tt.hash {
+				t.Errorf("hash got %v, want present=%v", h.Hash, tt.hash)
+			}
+			if g, w := h.String(), tt.in; g != w {
+				t.Errorf("String got %v, want %v", g, w)
+			}
+		})
+	}
+}
+
+func TestParsePHCInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"argon2id$v=19",
+		"$",
+		"$Argon2id",
+		"$argon2id$v=019",
+		"$argon2id$v=x",
+		"$argon2id$m=1,m=2",
+		"$argon2id$m=",
+		"$argon2id$M=1",
+		"$argon2id$m=1$!!!",
+		"$argon2id$m=1$c2FsdA$",
+		"$argon2id$m=1$c2FsdA$aGFzaA$extra",
+	}
+	for _, in := range tests {
+		t.Run(in, func(t *testing.T) {
+			if _, err := ParsePHC(in); !errors.Is(err, ErrInvalidFormat) {
+				t.Errorf("err got %v, want %v", err, ErrInvalidFormat)
+			}
+		})
+	}
+}
+
+func TestCompareHashAndPasswordPHCVariants(t *testing.T) {
+	// generated by the reference implementation:
+	// echo -n password | argon2 somesalt -id -t 2 -m 16 -p 1 -l 32
+	const encoded = "$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"
+	if err := CompareHashAndPassword(encoded, "password"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("reordered params", func(t *testing.T) {
+		reordered := "$argon2id$v=19$p=1,t=2,m=65536$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"
+		if err := CompareHashAndPassword(reordered, "password"); err != nil {
+			t.Errorf("err got %v, want nil", err)
+		}
+	})
+	t.Run("omitted version", func(t *testing.T) {
+		omitted := "$argon2id$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"
+		if err := CompareHashAndPassword(omitted, "password"); !errors.Is(err, ErrIncompatibleVersion) {
+			t.Errorf("err got %v, want %v", err, ErrIncompatibleVersion)
+		}
+	})
+	t.Run("unsupported algorithm", func(t *testing.T) {
+		other := "$scrypt$ln=16,r=8,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"
+		if err := CompareHashAndPassword(other, "password"); !errors.Is(err, ErrUnsupportedAlgorithm) {
+			t.Errorf("err got %v, want %v", err, ErrUnsupportedAlgorithm)
+		}
+	})
+	t.Run("invalid cost params", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			params string
+		}{
+			{name: "t=0", params: "m=65536,t=0,p=1"},
+			{name: "p=0", params: "m=65536,t=2,p=0"},
+			{name: "m=0", params: "m=0,t=2,p=1"},
+			{name: "m<8*p", params: "m=31,t=2,p=4"},
+			{name: "m>MaxMemory", params: "m=4294967295,t=2,p=1"},
+			{name: "t>MaxIterations", params: "m=8,t=33,p=1"},
+			{name: "t=MaxUint32", params: "m=8,t=4294967295,p=1"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				invalid := "$argon2id$v=19$" + tt.params + "$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"
+				if err := CompareHashAndPassword(invalid, "password"); !errors.Is(err, ErrInvalidFormat) {
+					t.Errorf("err got %v, want %v", err, ErrInvalidFormat)
+				}
+				if _, err := NeedsRehash(invalid, DefaultParams); !errors.Is(err, ErrInvalidFormat) {
+					t.Errorf("NeedsRehash err got %v, want %v", err, ErrInvalidFormat)
+				}
+			})
+		}
+	})
+	t.Run("missing params", func(t *testing.T) {
+		missing := "$argon2id$v=19$m=65536,t=2$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"
+		if err := CompareHashAndPassword(missing, "password"); !errors.Is(err, ErrInvalidFormat) {
+			t.Errorf("err got %v, want %v", err, ErrInvalidFormat)
+		}
+	})
+}
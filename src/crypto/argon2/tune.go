@@ -0,0 +1,97 @@
+package argon2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// ErrTargetTooShort is returned by Tune when even the minimum memory with one iteration exceeds the target.
+var ErrTargetTooShort = errors.New("argon2: target duration too short")
+
+// TuneOptions is the options of Tune.
+type TuneOptions struct {
+	// Variant is the Argon2 variant. The zero value is Argon2id.
+	Variant Variant
+	// Target is the maximum duration of a single hashing.
+	Target time.Duration
+	// MaxMemory is the memory ceiling in KiB.
+	MaxMemory uint32
+	// Parallelism is the number of lanes. If zero, runtime.NumCPU() (at most 255) is used.
+	Parallelism uint8
+	// SaltLength and KeyLength are copied to the result. If zero, those of the DefaultParams are used.
+	SaltLength uint32
+	KeyLength  uint32
+}
+
+// Tune benchmarks Argon2 on the current machine and returns the strongest Params that fit
+// the target duration and the memory ceiling, following the RFC 9106 section 4 procedure:
+// start with the maximum memory and one iteration, reduce the memory while the hashing is
+// slower than the target, and then increase the iterations while it is still within the target.
+// https://datatracker.ietf.org/doc/html/rfc9106#section-4
+func Tune(ctx context.Context, opts TuneOptions) (Params, error) {
+	return tune(ctx, opts, measure)
+}
+
+func measure(p Params) time.Duration {
+	password := []byte("password")
+	salt := make([]byte, p.SaltLength)
+	start := time.Now()
+	p.Variant.key(password, salt, p)
+	return time.Since(start)
+}
+
+func tune(ctx context.Context, opts TuneOptions, measure func(Params) time.Duration) (Params, error) {
+	p := Params{
+		Variant:     opts.Variant,
+		Memory:      opts.MaxMemory,
+		Iterations:  1,
+		Parallelism: opts.Parallelism,
+		SaltLength:  opts.SaltLength,
+		KeyLength:   opts.KeyLength,
+	}
+	if p.Parallelism == 0 {
+		p.Parallelism = uint8(min(runtime.NumCPU(), 255))
+	}
+	if p.SaltLength == 0 {
+		p.SaltLength = DefaultParams.SaltLength
+	}
+	if p.KeyLength == 0 {
+		p.KeyLength = DefaultParams.KeyLength
+	}
+	if err := p.Validate(); err != nil {
+		return Params{}, err
+	}
+	if opts.Target <= 0 {
+		return Params{}, fmt.Errorf("%w: %v", ErrTargetTooShort, opts.Target)
+	}
+
+	// reduce the memory until one iteration fits the target
+	minMemory := 8 * uint32(p.Parallelism)
+	for {
+		if err := ctx.Err(); err != nil {
+			return Params{}, err
+		}
+		if measure(p) <= opts.Target {
+			break
+		}
+		if p.Memory == minMemory {
+			return Params{}, fmt.Errorf("%w: m=%d,t=1,p=%d exceeds %v", ErrTargetTooShort, p.Memory, p.Parallelism, opts.Target)
+		}
+		p.Memory = max(p.Memory/2, minMemory)
+	}
+	// increase the iterations while it fits the target
+	for {
+		if err := ctx.Err(); err != nil {
+			return Params{}, err
+		}
+		next := p
+		next.Iterations++
+		if next.Iterations > MaxIterations || measure(next) > opts.Target {
+			return p, nil
+		}
+		p = next
+	}
+}
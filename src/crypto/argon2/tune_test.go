@@ -0,0 +1,84 @@
+package argon2
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeMeasure simulates a machine hashing 1 MiB per iteration in 1ms.
+func fakeMeasure(p Params) time.Duration {
+	return time.Duration(p.Memory/1024) * time.Duration(p.Iterations) * time.Millisecond
+}
+
+func TestTune(t *testing.T) {
+	tests := []struct {
+		name string
+		opts TuneOptions
+		want Params
+	}{
+		{
+			name: "reduce memory",
+			opts: TuneOptions{Target: 20 * time.Millisecond, MaxMemory: 64 * 1024, Parallelism: 4},
+			want: Params{Memory: 16 * 1024, Iterations: 1, Parallelism: 4, SaltLength: 16, KeyLength: 32},
+		},
+		{
+			name: "increase iterations",
+			opts: TuneOptions{Target: 200 * time.Millisecond, MaxMemory: 64 * 1024, Parallelism: 4},
+			want: Params{Memory: 64 * 1024, Iterations: 3, Parallelism: 4, SaltLength: 16, KeyLength: 32},
+		},
+		{
+			name: "argon2i",
+			opts: TuneOptions{Variant: Argon2i, Target: 64 * time.Millisecond, MaxMemory: 64 * 1024, Parallelism: 1, SaltLength: 32, KeyLength: 64},
+			want: Params{Variant: Argon2i, Memory: 64 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 32, KeyLength: 64},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tune(context.Background(), tt.opts, fakeMeasure)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("\ngot :%+v\nwant:%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTuneMaxIterations(t *testing.T) {
+	// a target too long for the memory ceiling stops at MaxIterations, so that the hashes can be verified
+	p, err := tune(context.Background(), TuneOptions{Target: time.Hour, MaxMemory: 64 * 1024, Parallelism: 4}, fakeMeasure)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Iterations != MaxIterations {
+		t.Errorf("iterations got %v, want %v", p.Iterations, MaxIterations)
+	}
+}
+
+func TestTuneTargetTooShort(t *testing.T) {
+	slow := func(Params) time.Duration { return time.Second }
+	_, err := tune(context.Background(), TuneOptions{Target: time.Millisecond, MaxMemory: 64 * 1024, Parallelism: 1}, slow)
+	if !errors.Is(err, ErrTargetTooShort) {
+		t.Errorf("err got %v, want %v", err, ErrTargetTooShort)
+	}
+}
+
+func TestTuneOnThisMachine(t *testing.T) {
+	p, err := Tune(context.Background(), TuneOptions{Target: 50 * time.Millisecond, MaxMemory: 16 * 1024, Parallelism: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Validate(); err != nil {
+		t.Error(err)
+	}
+	t.Log(p.PHCPrefix())
+}
+
+func TestParamsPHCPrefix(t *testing.T) {
+	if g, w := DefaultParams.PHCPrefix(), "$argon2id$v=19$m=65536,t=3,p=4"; g != w {
+		t.Errorf("got %v, want %v", g, w)
+	}
+}
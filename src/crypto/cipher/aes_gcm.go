@@ -12,6 +12,13 @@ import (
 // either 16, 24, or 32 bytes to select
 // AES-128, AES-192, or AES-256.
 func AESGCMEncrypt(key []byte, text []byte) (encrypted []byte, err error) {
+	return AESGCMEncryptWithAAD(key, text, nil)
+}
+
+// AESGCMEncryptWithAAD is like AESGCMEncrypt, but authenticates the additional data (aad) together.
+// The aad is not encrypted nor included in the result, and the same aad must be given to AESGCMDecryptWithAAD.
+// It binds the ciphertext to the context, e.g. the row ID and the column name.
+func AESGCMEncryptWithAAD(key, text, aad []byte) (encrypted []byte, err error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, fmt.Errorf("cipher: creates an aes cipher block: %w", err)
@@ -29,11 +36,18 @@ func AESGCMEncrypt(key []byte, text []byte) (encrypted []byte, err error) {
 		return nil, fmt.Errorf("cipher: creates a nonece: %w", err)
 	}
 	// Encrypt the text and append the ciphertext to the nonce.
-	encrypted = aesgcm.Seal(nonce, nonce, text, nil)
+	encrypted = aesgcm.Seal(nonce, nonce, text, aad)
 	return encrypted, nil
 }
 
+// AESGCMDecrypt decrypts the encrypted by AESGCMEncrypt.
 func AESGCMDecrypt(key, encrypted []byte) ([]byte, error) {
+	return AESGCMDecryptWithAAD(key, encrypted, nil)
+}
+
+// AESGCMDecryptWithAAD decrypts the encrypted by AESGCMEncryptWithAAD.
+// It returns an error if the aad differs from the one given on encryption.
+func AESGCMDecryptWithAAD(key, encrypted, aad []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, fmt.Errorf("cipher: creates an aes cipher block: %w", err)
@@ -48,7 +62,7 @@ func AESGCMDecrypt(key, encrypted []byte) ([]byte, error) {
 	}
 	nonce := encrypted[:nonceSize]
 	ciphertext := encrypted[nonceSize:]
-	text, err := aesgcm.Open(nil, nonce, ciphertext, nil)
+	text, err := aesgcm.Open(nil, nonce, ciphertext, aad)
 	if err != nil {
 		return nil, fmt.Errorf("cipher: decrypt encrypted: %w", err)
 	}
@@ -26,3 +26,32 @@ func TestAES256GCM(t *testing.T) {
 		t.Errorf("plaintext got %v, want %v", g, w)
 	}
 }
+
+func TestAES256GCMWithAAD(t *testing.T) {
+	key256 := make([]byte, 32)
+	if _, err := rand.Read(key256); err != nil {
+		t.Fatal(err)
+	}
+	text := []byte("test test test")
+	aad := []byte("users:42:email")
+
+	ciphertext, err := AESGCMEncryptWithAAD(key256, text, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := AESGCMDecryptWithAAD(key256, ciphertext, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, w := string(plaintext), string(text); g != w {
+		t.Errorf("plaintext got %v, want %v", g, w)
+	}
+
+	// copied to another row
+	if _, err := AESGCMDecryptWithAAD(key256, ciphertext, []byte("users:43:email")); err == nil {
+		t.Error("mismatched aad should fail to open")
+	}
+	if _, err := AESGCMDecrypt(key256, ciphertext); err == nil {
+		t.Error("missing aad should fail to open")
+	}
+}
@@ -0,0 +1,151 @@
+package cipher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"fmt"
+)
+
+// AES-SIV (RFC 5297) is a deterministic authenticated encryption.
+// The same key, text and associated data always produce the same ciphertext,
+// so that the encrypted values can be looked up by equality, e.g. an encrypted email column.
+// It reveals whether two ciphertexts share the same plaintext, but nothing else,
+// and it is misuse-resistant: there is no nonce to repeat.
+// https://datatracker.ietf.org/doc/html/rfc5297
+
+// sivSize is the size of the synthetic IV prepended to the ciphertext.
+const sivSize = aes.BlockSize
+
+// AESSIVEncrypt encrypts the text deterministically.
+// The key argument should be either 32, 48, or 64 bytes to select
+// AES-SIV-256, AES-SIV-384, or AES-SIV-512 (the first half is for S2V and the second half is for CTR).
+func AESSIVEncrypt(key, text []byte) ([]byte, error) {
+	return AESSIVEncryptWithAAD(key, text)
+}
+
+// AESSIVEncryptWithAAD is like AESSIVEncrypt, but also authenticates the associated data components.
+// A nonce may be given as the last component to make the encryption non-deterministic.
+func AESSIVEncryptWithAAD(key, text []byte, aad ...[]byte) ([]byte, error) {
+	macBlock, ctrBlock, err := newSIVBlocks(key)
+	if err != nil {
+		return nil, err
+	}
+	v := s2v(macBlock, aad, text)
+	encrypted := make([]byte, sivSize+len(text))
+	copy(encrypted, v)
+	sivCTR(ctrBlock, v, encrypted[sivSize:], text)
+	return encrypted, nil
+}
+
+// AESSIVDecrypt decrypts the encrypted by AESSIVEncrypt.
+func AESSIVDecrypt(key, encrypted []byte) ([]byte, error) {
+	return AESSIVDecryptWithAAD(key, encrypted)
+}
+
+// AESSIVDecryptWithAAD decrypts the encrypted by AESSIVEncryptWithAAD.
+// It returns an error if the associated data components differ from those given on encryption.
+func AESSIVDecryptWithAAD(key, encrypted []byte, aad ...[]byte) ([]byte, error) {
+	macBlock, ctrBlock, err := newSIVBlocks(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(encrypted) < sivSize {
+		return nil, fmt.Errorf("cipher: invalid encrypted value")
+	}
+	v, ciphertext := encrypted[:sivSize], encrypted[sivSize:]
+	text := make([]byte, len(ciphertext))
+	sivCTR(ctrBlock, v, text, ciphertext)
+	if subtle.ConstantTimeCompare(v, s2v(macBlock, aad, text)) != 1 {
+		clear(text)
+		return nil, fmt.Errorf("cipher: decrypt encrypted: message authentication failed")
+	}
+	return text, nil
+}
+
+func newSIVBlocks(key []byte) (macBlock, ctrBlock cipher.Block, err error) {
+	if n := len(key); n != 32 && n != 48 && n != 64 {
+		return nil, nil, fmt.Errorf("cipher: invalid AES-SIV key size %d", n)
+	}
+	half := len(key) / 2
+	if macBlock, err = aes.NewCipher(key[:half]); err != nil {
+		return nil, nil, fmt.Errorf("cipher: creates an aes cipher block: %w", err)
+	}
+	if ctrBlock, err = aes.NewCipher(key[half:]); err != nil {
+		return nil, nil, fmt.Errorf("cipher: creates an aes cipher block: %w", err)
+	}
+	return macBlock, ctrBlock, nil
+}
+
+// sivCTR XORs the src with the AES-CTR keystream starting from the v with the 31st and 63rd bits cleared.
+func sivCTR(block cipher.Block, v, dst, src []byte) {
+	q := make([]byte, sivSize)
+	copy(q, v)
+	q[8] &= 0x7f
+	q[12] &= 0x7f
+	cipher.NewCTR(block, q).XORKeyStream(dst, src)
+}
+
+// s2v is the S2V operation of RFC 5297 section 2.4 over the aad components followed by the text.
+func s2v(block cipher.Block, aad [][]byte, text []byte) []byte {
+	d := cmac(block, make([]byte, aes.BlockSize))
+	for _, s := range aad {
+		dbl(d)
+		subtle.XORBytes(d, d, cmac(block, s))
+	}
+	var t []byte
+	if len(text) >= aes.BlockSize {
+		// T = Sn xorend D
+		t = append([]byte(nil), text...)
+		tail := t[len(t)-aes.BlockSize:]
+		subtle.XORBytes(tail, tail, d)
+	} else {
+		// T = dbl(D) xor pad(Sn)
+		dbl(d)
+		t = make([]byte, aes.BlockSize)
+		copy(t, text)
+		t[len(text)] = 0x80
+		subtle.XORBytes(t, t, d)
+	}
+	return cmac(block, t)
+}
+
+// cmac is AES-CMAC of RFC 4493.
+func cmac(block cipher.Block, msg []byte) []byte {
+	k := make([]byte, aes.BlockSize)
+	block.Encrypt(k, k)
+	dbl(k) // K1
+
+	last := make([]byte, aes.BlockSize)
+	n := (len(msg) + aes.BlockSize - 1) / aes.BlockSize
+	if n > 0 && len(msg)%aes.BlockSize == 0 {
+		copy(last, msg[(n-1)*aes.BlockSize:])
+	} else {
+		dbl(k) // K2
+		if n == 0 {
+			n = 1
+		}
+		rest := msg[(n-1)*aes.BlockSize:]
+		copy(last, rest)
+		last[len(rest)] = 0x80
+	}
+	subtle.XORBytes(last, last, k)
+
+	x := make([]byte, aes.BlockSize)
+	for i := 0; i < n-1; i++ {
+		subtle.XORBytes(x, x, msg[i*aes.BlockSize:(i+1)*aes.BlockSize])
+		block.Encrypt(x, x)
+	}
+	subtle.XORBytes(x, x, last)
+	block.Encrypt(x, x)
+	return x
+}
+
+// dbl multiplies the b by x in GF(2^128) in place.
+func dbl(b []byte) {
+	carry := b[0] >> 7
+	for i := 0; i < len(b)-1; i++ {
+		b[i] = b[i]<<1 | b[i+1]>>7
+	}
+	b[len(b)-1] = b[len(b)-1]<<1 ^ 0x87*carry
+}
@@ -0,0 +1,136 @@
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestAESSIVRFC5297(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    string
+		aad    []string
+		text   string
+		output string
+	}{
+		{
+			name: "A.1 deterministic authenticated encryption",
+			key:  "fffefdfc fbfaf9f8 f7f6f5f4 f3f2f1f0 f0f1f2f3 f4f5f6f7 f8f9fafb fcfdfeff",
+			aad:  []string{"10111213 14151617 18191a1b 1c1d1e1f 20212223 24252627"},
+			text: "11223344 55667788 99aabbcc ddee",
+			output: "85632d07 c6e8f37f 950acd32 0a2ecc93" +
+				"40c02b96 90c4dc04 daef7f6a fe5c",
+		},
+		{
+			name: "A.2 nonce-based authenticated encryption",
+			key:  "7f7e7d7c 7b7a7978 77767574 73727170 40414243 44454647 48494a4b 4c4d4e4f",
+			aad: []string{
+				"00112233 44556677 8899aabb ccddeeff deaddada deaddada ffeeddcc bbaa9988 77665544 33221100",
+				"10203040 50607080 90a0",
+				// nonce
+				"09f91102 9d74e35b d84156c5 635688c0",
+			},
+			text: "74686973 20697320 736f6d65 20706c61 696e7465 78742074 6f20656e 63727970 74207573 696e6720 5349562d 414553",
+			output: "7bdb6e3b 432667eb 06f4d14b ff2fbd0f" +
+				"cb900f2f ddbe4043 26601965 c889bf17 dba77ceb 094fa663 b7a3f748 ba8af829 ea64ad54 4a272e9c 485b62a3 fd5c0d",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := mustHex(t, tt.key)
+			var aad [][]byte
+			for _, a := range tt.aad {
+				aad = append(aad, mustHex(t, a))
+			}
+			text := mustHex(t, tt.text)
+			want := mustHex(t, tt.output)
+
+			got, err := AESSIVEncryptWithAAD(key, text, aad...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("\ngot :%x\nwant:%x", got, want)
+			}
+			decrypted, err := AESSIVDecryptWithAAD(key, want, aad...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(decrypted, text) {
+				t.Errorf("\ngot :%x\nwant:%x", decrypted, text)
+			}
+		})
+	}
+}
+
+func TestCMACRFC4493(t *testing.T) {
+	key := mustHex(t, "2b7e1516 28aed2a6 abf71588 09cf4f3c")
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := mustHex(t, "6bc1bee2 2e409f96 e93d7e11 7393172a ae2d8a57 1e03ac9c 9eb76fac 45af8e51 30c81c46 a35ce411 e5fbc119 1a0a52ef f69f2445 df4f9b17 ad2b417b e66c3710")
+	tests := []struct {
+		len int
+		mac string
+	}{
+		{len: 0, mac: "bb1d6929 e9593728 7fa37d12 9b756746"},
+		{len: 16, mac: "070a16b4 6b4d4144 f79bdd9d d04a287c"},
+		{len: 40, mac: "dfa66747 de9ae630 30ca3261 1497c827"},
+		{len: 64, mac: "51f0bebf 7e3b9d92 fc497417 79363cfe"},
+	}
+	for _, tt := range tests {
+		if g, w := cmac(block, msg[:tt.len]), mustHex(t, tt.mac); !bytes.Equal(g, w) {
+			t.Errorf("len %d:\ngot :%x\nwant:%x", tt.len, g, w)
+		}
+	}
+}
+
+func TestAESSIVDeterministic(t *testing.T) {
+	key := make([]byte, 64)
+	e1, err := AESSIVEncrypt(key, []byte("alice@example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e2, err := AESSIVEncrypt(key, []byte("alice@example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(e1, e2) {
+		t.Error("same text should produce the same ciphertext")
+	}
+	e3, err := AESSIVEncrypt(key, []byte("bob@example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(e1, e3) {
+		t.Error("different text should produce different ciphertext")
+	}
+
+	decrypted, err := AESSIVDecrypt(key, e1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, w := string(decrypted), "alice@example.com"; g != w {
+		t.Errorf("\ngot :%v\nwant:%v", g, w)
+	}
+	e1[len(e1)-1] ^= 1
+	if _, err := AESSIVDecrypt(key, e1); err == nil {
+		t.Error("tampered ciphertext should fail to open")
+	}
+	if _, err := AESSIVEncrypt(make([]byte, 16), nil); err == nil {
+		t.Error("invalid key size should fail")
+	}
+}
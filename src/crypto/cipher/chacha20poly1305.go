@@ -12,6 +12,13 @@ import (
 // * nonce が 192bit と長く、カウンターではなく乱数を使って nonce を生成する場合に比較的安全に使うことができる
 // * 同じ鍵で実質的に無制限の数のメッセージを安全に暗号化でき、メッセージのサイズに実用的な制限がない（最大2^64バイトまで）
 func XChaCha20Poly1305Encrypt(key, text []byte) ([]byte, error) {
+	return XChaCha20Poly1305EncryptWithAAD(key, text, nil)
+}
+
+// XChaCha20Poly1305EncryptWithAAD は XChaCha20Poly1305Encrypt と同様に text を暗号化し、追加データ aad も合わせて認証する。
+// aad は暗号化されず結果にも含まれないため、復号時には同じ aad を XChaCha20Poly1305DecryptWithAAD に渡すこと。
+// 行 ID やカラム名などを aad にすることで、暗号文を別の行にコピーしても復号できないよう文脈に束縛できる。
+func XChaCha20Poly1305EncryptWithAAD(key, text, aad []byte) ([]byte, error) {
 	aead, err := chacha20poly1305.NewX(key)
 	if err != nil {
 		return nil, fmt.Errorf("cipher: create aead: %w", err)
@@ -22,20 +29,30 @@ func XChaCha20Poly1305Encrypt(key, text []byte) ([]byte, error) {
 		return nil, fmt.Errorf("cipher: read rand: %w", err)
 	}
 	// Encrypt the message and append the ciphertext to the nonce.
-	encrypted := aead.Seal(nonce, nonce, text, nil)
+	encrypted := aead.Seal(nonce, nonce, text, aad)
 	return encrypted, nil
 }
 
+// XChaCha20Poly1305Decrypt は XChaCha20Poly1305Encrypt で暗号化された encrypted を復号する。
 func XChaCha20Poly1305Decrypt(key, encrypted []byte) ([]byte, error) {
+	return XChaCha20Poly1305DecryptWithAAD(key, encrypted, nil)
+}
+
+// XChaCha20Poly1305DecryptWithAAD は XChaCha20Poly1305EncryptWithAAD で暗号化された encrypted を復号する。
+// aad が暗号化時と異なる場合はエラーを返す。
+func XChaCha20Poly1305DecryptWithAAD(key, encrypted, aad []byte) ([]byte, error) {
 	aead, err := chacha20poly1305.NewX(key)
 	if err != nil {
 		return nil, fmt.Errorf("cipher: create aead: %w", err)
 	}
 	// Split nonce and ciphertext.
 	nonceSize := aead.NonceSize()
+	if len(encrypted) < nonceSize {
+		return nil, fmt.Errorf("cipher: invalid encrypted value")
+	}
 	nonce, ciphertext := encrypted[:nonceSize], encrypted[nonceSize:]
 	// Decrypt the message and check it wasn't tampered with.
-	text, err := aead.Open(nil, nonce, ciphertext, nil)
+	text, err := aead.Open(nil, nonce, ciphertext, aad)
 	if err != nil {
 		return nil, fmt.Errorf("cipher: decrypt: %w", err)
 	}
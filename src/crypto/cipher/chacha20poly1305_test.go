@@ -22,3 +22,33 @@ func TestXChaCha20Poly1305EncryptDecrypt(t *testing.T) {
 		t.Errorf("\ngot :%v\nwant:%v", string(decrypted), "foo bar baz")
 	}
 }
+
+func TestXChaCha20Poly1305EncryptDecryptWithAAD(t *testing.T) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	aad := []byte("users:42:email")
+	encrypted, err := XChaCha20Poly1305EncryptWithAAD(key, []byte("foo bar baz"), aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := XChaCha20Poly1305DecryptWithAAD(key, encrypted, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, w := string(decrypted), "foo bar baz"; g != w {
+		t.Errorf("\ngot :%v\nwant:%v", g, w)
+	}
+
+	// copied to another row
+	if _, err := XChaCha20Poly1305DecryptWithAAD(key, encrypted, []byte("users:43:email")); err == nil {
+		t.Error("mismatched aad should fail to open")
+	}
+	if _, err := XChaCha20Poly1305Decrypt(key, encrypted); err == nil {
+		t.Error("missing aad should fail to open")
+	}
+	if _, err := XChaCha20Poly1305Decrypt(key, []byte("short")); err == nil {
+		t.Error("short encrypted should fail to open")
+	}
+}
@@ -0,0 +1,158 @@
+package cipher
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// KeyWrapper wraps (encrypts) and unwraps data keys with a key-encryption key (KEK).
+// The wrapped key is opaque, and must carry what is needed to unwrap it, e.g. the KEK id.
+// It can be implemented with a cloud KMS.
+type KeyWrapper interface {
+	WrapKey(ctx context.Context, dataKey []byte) (wrapped []byte, err error)
+	UnwrapKey(ctx context.Context, wrapped []byte) (dataKey []byte, err error)
+}
+
+// The sealed data of EnvelopeEncrypt is as follows:
+//
+//	version (1 byte) || wrapped key length (2 bytes, big endian) || wrapped key || nonce || ciphertext
+const sealedVersion1 = 1
+
+// EnvelopeEncrypt encrypts the text with a random data key using XChaCha20-Poly1305,
+// and returns the sealed data including the data key wrapped by the w.
+func EnvelopeEncrypt(ctx context.Context, w KeyWrapper, text, aad []byte) ([]byte, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("cipher: creates a data key: %w", err)
+	}
+	defer clear(dataKey)
+
+	wrapped, err := w.WrapKey(ctx, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("cipher: wrap data key: %w", err)
+	}
+	encrypted, err := XChaCha20Poly1305EncryptWithAAD(dataKey, text, aad)
+	if err != nil {
+		return nil, err
+	}
+	return marshalSealed(wrapped, encrypted)
+}
+
+// EnvelopeDecrypt unwraps the data key of the sealed data by the w, and decrypts the text with it.
+func EnvelopeDecrypt(ctx context.Context, w KeyWrapper, sealed, aad []byte) ([]byte, error) {
+	wrapped, encrypted, err := unmarshalSealed(sealed)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := w.UnwrapKey(ctx, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("cipher: unwrap data key: %w", err)
+	}
+	defer clear(dataKey)
+	return XChaCha20Poly1305DecryptWithAAD(dataKey, encrypted, aad)
+}
+
+// EnvelopeRewrap unwraps the data key of the sealed data by the from, and wraps it again by the to.
+// The payload is not re-encrypted, so rotating the KEK only requires rewrapping the data keys.
+// The from and the to may be the same KeyWrapper retaining the old KEK and wrapping with the new one.
+func EnvelopeRewrap(ctx context.Context, sealed []byte, from, to KeyWrapper) ([]byte, error) {
+	wrapped, encrypted, err := unmarshalSealed(sealed)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := from.UnwrapKey(ctx, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("cipher: unwrap data key: %w", err)
+	}
+	defer clear(dataKey)
+	rewrapped, err := to.WrapKey(ctx, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("cipher: wrap data key: %w", err)
+	}
+	return marshalSealed(rewrapped, encrypted)
+}
+
+func marshalSealed(wrapped, encrypted []byte) ([]byte, error) {
+	if len(wrapped) > 0xffff {
+		return nil, fmt.Errorf("cipher: wrapped key too long %d", len(wrapped))
+	}
+	sealed := make([]byte, 0, 3+len(wrapped)+len(encrypted))
+	sealed = append(sealed, sealedVersion1)
+	sealed = binary.BigEndian.AppendUint16(sealed, uint16(len(wrapped)))
+	sealed = append(sealed, wrapped...)
+	return append(sealed, encrypted...), nil
+}
+
+func unmarshalSealed(sealed []byte) (wrapped, encrypted []byte, err error) {
+	if len(sealed) < 3 {
+		return nil, nil, fmt.Errorf("%w: too short", ErrInvalidEnvelope)
+	}
+	if v := sealed[0]; v != sealedVersion1 {
+		return nil, nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidEnvelope, v)
+	}
+	n := 3 + int(binary.BigEndian.Uint16(sealed[1:3]))
+	if len(sealed) < n {
+		return nil, nil, fmt.Errorf("%w: too short", ErrInvalidEnvelope)
+	}
+	return sealed[3:n], sealed[n:], nil
+}
+
+// LocalKeyWrapper is a KeyWrapper with the KEKs held in a local file.
+// The data keys are wrapped by the Keyring, so the wrapped key records the KEK id.
+type LocalKeyWrapper struct {
+	keyring *Keyring
+}
+
+var _ KeyWrapper = (*LocalKeyWrapper)(nil)
+
+// localKEKFile is the JSON format of the LocalKeyWrapper file, e.g.
+//
+//	{"active": "kek-2", "keys": [{"id": "kek-1", "key": "<base64>"}, {"id": "kek-2", "key": "<base64>"}]}
+//
+// The keys are 32 bytes base64 encoded, and used with XChaCha20-Poly1305.
+type localKEKFile struct {
+	Active string `json:"active"`
+	Keys   []struct {
+		ID  string `json:"id"`
+		Key string `json:"key"`
+	} `json:"keys"`
+}
+
+// LoadLocalKeyWrapper loads the KEKs from the JSON file at the path.
+// The data keys are wrapped by the active KEK, and unwrapped by any KEK in the file.
+func LoadLocalKeyWrapper(path string) (*LocalKeyWrapper, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cipher: read kek file: %w", err)
+	}
+	var f localKEKFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("cipher: unmarshal kek file: %w", err)
+	}
+	keys := make([]Key, 0, len(f.Keys))
+	for _, k := range f.Keys {
+		secret, err := base64.StdEncoding.DecodeString(k.Key)
+		if err != nil {
+			return nil, fmt.Errorf("cipher: decode kek %q: %w", k.ID, err)
+		}
+		keys = append(keys, Key{ID: k.ID, Algorithm: AlgorithmXChaCha20Poly1305, Secret: secret})
+	}
+	keyring, err := NewKeyring(f.Active, keys...)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalKeyWrapper{keyring: keyring}, nil
+}
+
+func (l *LocalKeyWrapper) WrapKey(_ context.Context, dataKey []byte) ([]byte, error) {
+	return l.keyring.Encrypt(dataKey)
+}
+
+func (l *LocalKeyWrapper) UnwrapKey(_ context.Context, wrapped []byte) ([]byte, error) {
+	return l.keyring.Decrypt(wrapped)
+}
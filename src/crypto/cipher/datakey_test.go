@@ -0,0 +1,110 @@
+package cipher
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeKEKFile(t *testing.T, active string, ids ...string) string {
+	t.Helper()
+	keys := ""
+	for i, id := range ids {
+		key := make([]byte, 32)
+		// deterministic keys per id, so that the files share the same KEK
+		copy(key, id)
+		if i > 0 {
+			keys += ","
+		}
+		keys += fmt.Sprintf(`{"id": %q, "key": %q}`, id, base64.StdEncoding.EncodeToString(key))
+	}
+	path := filepath.Join(t.TempDir(), "kek.json")
+	if err := os.WriteFile(path, fmt.Appendf(nil, `{"active": %q, "keys": [%s]}`, active, keys), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestEnvelopeEncrypt(t *testing.T) {
+	ctx := context.Background()
+	w, err := LoadLocalKeyWrapper(writeKEKFile(t, "kek-1", "kek-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := make([]byte, 1000)
+	if _, err := rand.Read(text); err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := EnvelopeEncrypt(ctx, w, text, []byte("aad"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := EnvelopeDecrypt(ctx, w, sealed, []byte("aad"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decrypted) != string(text) {
+		t.Errorf("decrypted does not match")
+	}
+	if _, err := EnvelopeDecrypt(ctx, w, sealed, []byte("other")); err == nil {
+		t.Error("mismatched aad should fail to open")
+	}
+	if _, err := EnvelopeDecrypt(ctx, w, sealed[:2], nil); !errors.Is(err, ErrInvalidEnvelope) {
+		t.Errorf("err got %v, want %v", err, ErrInvalidEnvelope)
+	}
+}
+
+func TestEnvelopeRewrap(t *testing.T) {
+	ctx := context.Background()
+	old, err := LoadLocalKeyWrapper(writeKEKFile(t, "kek-1", "kek-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := EnvelopeEncrypt(ctx, old, []byte("foo bar baz"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// rotate the KEK retaining the old one
+	rotated, err := LoadLocalKeyWrapper(writeKEKFile(t, "kek-2", "kek-1", "kek-2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rewrapped, err := EnvelopeRewrap(ctx, sealed, rotated, rotated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the payload is not re-encrypted
+	oldWrapped, oldEncrypted, _ := unmarshalSealed(sealed)
+	newWrapped, newEncrypted, _ := unmarshalSealed(rewrapped)
+	if string(oldEncrypted) != string(newEncrypted) {
+		t.Error("payload should not be re-encrypted")
+	}
+	if id, _ := KeyID(oldWrapped); id != "kek-1" {
+		t.Errorf("old kek id got %v, want kek-1", id)
+	}
+	if id, _ := KeyID(newWrapped); id != "kek-2" {
+		t.Errorf("new kek id got %v, want kek-2", id)
+	}
+
+	// the old KEK can be retired after rewrapping
+	retired, err := LoadLocalKeyWrapper(writeKEKFile(t, "kek-2", "kek-2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := EnvelopeDecrypt(ctx, retired, rewrapped, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, w := string(decrypted), "foo bar baz"; g != w {
+		t.Errorf("\ngot :%v\nwant:%v", g, w)
+	}
+	if _, err := EnvelopeDecrypt(ctx, retired, sealed, nil); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("err got %v, want %v", err, ErrUnknownKeyID)
+	}
+}
@@ -0,0 +1,149 @@
+package cipher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrKeyExhausted is returned when the GCMSealer has sealed GCMMessageLimit messages with the key.
+// The key must be rotated.
+var ErrKeyExhausted = errors.New("cipher: key exhausted, rotate the key")
+
+// GCMMessageLimit is the maximum number of messages sealed with a key by the GCMSealer.
+const GCMMessageLimit = 1 << 32
+
+// gcmCounterReserve is the number of counters reserved in the CounterStore at once.
+// The counters reserved but not used before a restart are skipped.
+const gcmCounterReserve = 4096
+
+// CounterStore persists the nonce counter of the GCMSealer.
+type CounterStore interface {
+	// Load returns the stored counter. It returns 0 if nothing is stored yet.
+	Load() (uint64, error)
+	// Store stores the counter.
+	Store(counter uint64) error
+}
+
+// GCMSealer seals messages with AES-GCM using a monotonic counter as the nonce,
+// instead of the random nonce of AESGCMEncrypt.
+// The nonce is 4 zero bytes followed by the 8 bytes big-endian counter,
+// and the result nonce||ciphertext can be decrypted by AESGCMDecryptWithAAD.
+// A GCMSealer is safe for concurrent use, but the key must not be shared with other GCMSealers
+// or random nonce encryption.
+type GCMSealer struct {
+	aesgcm cipher.AEAD
+	store  CounterStore
+
+	mu       sync.Mutex
+	next     uint64
+	reserved uint64 // counters below this are reserved in the store
+}
+
+// NewGCMSealer returns a GCMSealer. The key should be the AES key, either 16, 24, or 32 bytes.
+// The store persists the counter across restarts. If the store is nil, the counter starts at 0,
+// so a nil store is safe only for a key that is never reused after the GCMSealer is discarded.
+func NewGCMSealer(key []byte, store CounterStore) (*GCMSealer, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("cipher: creates an aes cipher block: %w", err)
+	}
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("cipher: creates an aesgcm: %w", err)
+	}
+	s := &GCMSealer{aesgcm: aesgcm, store: store}
+	if store != nil {
+		c, err := store.Load()
+		if err != nil {
+			return nil, fmt.Errorf("cipher: load counter: %w", err)
+		}
+		s.next, s.reserved = c, c
+	}
+	return s, nil
+}
+
+// Seal encrypts the text and authenticates the aad, and returns nonce||ciphertext.
+// It returns ErrKeyExhausted once GCMMessageLimit messages have been sealed.
+func (s *GCMSealer) Seal(text, aad []byte) ([]byte, error) {
+	counter, err := s.nextCounter()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, s.aesgcm.NonceSize(), s.aesgcm.NonceSize()+len(text)+s.aesgcm.Overhead())
+	binary.BigEndian.PutUint64(nonce[4:], counter)
+	return s.aesgcm.Seal(nonce, nonce, text, aad), nil
+}
+
+// Remaining returns the number of messages the GCMSealer can still seal with the key.
+func (s *GCMSealer) Remaining() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.next >= GCMMessageLimit {
+		return 0
+	}
+	return GCMMessageLimit - s.next
+}
+
+func (s *GCMSealer) nextCounter() (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.next >= GCMMessageLimit {
+		return 0, ErrKeyExhausted
+	}
+	// persist the reservation before using the counter, so that it is never reused after a crash
+	if s.store != nil && s.next >= s.reserved {
+		reserved := min(s.next+gcmCounterReserve, GCMMessageLimit)
+		if err := s.store.Store(reserved); err != nil {
+			return 0, fmt.Errorf("cipher: store counter: %w", err)
+		}
+		s.reserved = reserved
+	}
+	c := s.next
+	s.next++
+	return c, nil
+}
+
+// FileCounterStore is a CounterStore storing the counter in a file as a decimal.
+type FileCounterStore struct {
+	Path string
+}
+
+func (f *FileCounterStore) Load() (uint64, error) {
+	b, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+}
+
+func (f *FileCounterStore) Store(counter uint64) error {
+	// write to a temporary file and rename it, so that a crash does not leave a broken file
+	tmp, err := os.CreateTemp(filepath.Dir(f.Path), filepath.Base(f.Path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(strconv.FormatUint(counter, 10)); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.Path)
+}
@@ -0,0 +1,94 @@
+package cipher
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestGCMSealer(t *testing.T) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewGCMSealer(key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nonces [][]byte
+	for range 3 {
+		encrypted, err := s.Seal([]byte("foo"), []byte("aad"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		decrypted, err := AESGCMDecryptWithAAD(key, encrypted, []byte("aad"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g, w := string(decrypted), "foo"; g != w {
+			t.Errorf("\ngot :%v\nwant:%v", g, w)
+		}
+		nonces = append(nonces, encrypted[:12])
+	}
+	for i, w := range [][]byte{
+		{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+		{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2},
+	} {
+		if !bytes.Equal(nonces[i], w) {
+			t.Errorf("nonce[%d] got %x, want %x", i, nonces[i], w)
+		}
+	}
+}
+
+func TestGCMSealerKeyExhausted(t *testing.T) {
+	s, err := NewGCMSealer(make([]byte, 16), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.next = GCMMessageLimit - 1
+	if _, err := s.Seal([]byte("foo"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if g := s.Remaining(); g != 0 {
+		t.Errorf("remaining got %v, want 0", g)
+	}
+	if _, err := s.Seal([]byte("foo"), nil); !errors.Is(err, ErrKeyExhausted) {
+		t.Errorf("err got %v, want %v", err, ErrKeyExhausted)
+	}
+}
+
+func TestGCMSealerFileCounterStore(t *testing.T) {
+	key := make([]byte, 32)
+	store := &FileCounterStore{Path: filepath.Join(t.TempDir(), "counter")}
+
+	s1, err := NewGCMSealer(key, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e1, err := s1.Seal([]byte("foo"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := store.Load(); c != gcmCounterReserve {
+		t.Errorf("stored counter got %v, want %v", c, gcmCounterReserve)
+	}
+
+	// restarted sealer skips the reserved counters
+	s2, err := NewGCMSealer(key, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e2, err := s2.Seal([]byte("foo"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(e1[:12], e2[:12]) {
+		t.Errorf("nonce reused after restart: %x", e1[:12])
+	}
+	if g, w := s2.Remaining(), uint64(GCMMessageLimit-gcmCounterReserve-1); g != w {
+		t.Errorf("remaining got %v, want %v", g, w)
+	}
+}
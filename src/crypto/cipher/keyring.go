@@ -0,0 +1,169 @@
+package cipher
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUnknownKeyID is returned when the Keyring does not have the key of the envelope.
+	ErrUnknownKeyID = errors.New("cipher: unknown key id")
+	// ErrInvalidEnvelope is returned when the envelope is malformed or has an unsupported version or algorithm.
+	ErrInvalidEnvelope = errors.New("cipher: invalid envelope")
+)
+
+// Algorithm identifies the AEAD algorithm of the envelope.
+type Algorithm byte
+
+const (
+	// AlgorithmAESGCM is AES-GCM by AESGCMEncryptWithAAD.
+	AlgorithmAESGCM Algorithm = 1
+	// AlgorithmXChaCha20Poly1305 is XChaCha20-Poly1305 by XChaCha20Poly1305EncryptWithAAD.
+	AlgorithmXChaCha20Poly1305 Algorithm = 2
+)
+
+func (a Algorithm) String() string {
+	switch a {
+	case AlgorithmAESGCM:
+		return "AES-GCM"
+	case AlgorithmXChaCha20Poly1305:
+		return "XChaCha20-Poly1305"
+	default:
+		return fmt.Sprintf("Algorithm(%d)", byte(a))
+	}
+}
+
+func (a Algorithm) validateKey(secret []byte) error {
+	switch a {
+	case AlgorithmAESGCM:
+		if n := len(secret); n != 16 && n != 24 && n != 32 {
+			return fmt.Errorf("cipher: invalid %v key size %d", a, n)
+		}
+	case AlgorithmXChaCha20Poly1305:
+		if n := len(secret); n != 32 {
+			return fmt.Errorf("cipher: invalid %v key size %d", a, n)
+		}
+	default:
+		return fmt.Errorf("cipher: unsupported algorithm %v", a)
+	}
+	return nil
+}
+
+func (a Algorithm) seal(secret, text, aad []byte) ([]byte, error) {
+	if a == AlgorithmAESGCM {
+		return AESGCMEncryptWithAAD(secret, text, aad)
+	}
+	return XChaCha20Poly1305EncryptWithAAD(secret, text, aad)
+}
+
+func (a Algorithm) open(secret, encrypted, aad []byte) ([]byte, error) {
+	if a == AlgorithmAESGCM {
+		return AESGCMDecryptWithAAD(secret, encrypted, aad)
+	}
+	return XChaCha20Poly1305DecryptWithAAD(secret, encrypted, aad)
+}
+
+// Key is a key of the Keyring.
+type Key struct {
+	// ID identifies the key in the envelope. It must be 1 to 255 bytes.
+	ID        string
+	Algorithm Algorithm
+	Secret    []byte
+}
+
+// Keyring encrypts with the active key and decrypts with any retained key.
+// The result is a self-describing envelope:
+//
+//	version (1 byte) || algorithm (1 byte) || key id length (1 byte) || key id || nonce || ciphertext
+//
+// The header before the nonce is authenticated as the additional data,
+// so that it cannot be altered to select another key or algorithm.
+type Keyring struct {
+	active string
+	keys   map[string]Key
+}
+
+const envelopeVersion1 = 1
+
+// NewKeyring returns a Keyring. The activeID must be the ID of one of the keys.
+func NewKeyring(activeID string, keys ...Key) (*Keyring, error) {
+	k := &Keyring{active: activeID, keys: make(map[string]Key, len(keys))}
+	for _, key := range keys {
+		if len(key.ID) < 1 || 255 < len(key.ID) {
+			return nil, fmt.Errorf("cipher: key id %q must be 1 to 255 bytes", key.ID)
+		}
+		if err := key.Algorithm.validateKey(key.Secret); err != nil {
+			return nil, err
+		}
+		if _, dup := k.keys[key.ID]; dup {
+			return nil, fmt.Errorf("cipher: duplicate key id %q", key.ID)
+		}
+		key.Secret = append([]byte(nil), key.Secret...)
+		k.keys[key.ID] = key
+	}
+	if _, ok := k.keys[activeID]; !ok {
+		return nil, fmt.Errorf("%w: active key id %q", ErrUnknownKeyID, activeID)
+	}
+	return k, nil
+}
+
+// Encrypt encrypts the text with the active key and returns the envelope.
+func (k *Keyring) Encrypt(text []byte) ([]byte, error) {
+	return k.EncryptWithAAD(text, nil)
+}
+
+// EncryptWithAAD is like Encrypt, but also authenticates the additional data (aad).
+func (k *Keyring) EncryptWithAAD(text, aad []byte) ([]byte, error) {
+	key := k.keys[k.active]
+	header := make([]byte, 0, 3+len(key.ID))
+	header = append(header, envelopeVersion1, byte(key.Algorithm), byte(len(key.ID)))
+	header = append(header, key.ID...)
+	encrypted, err := key.Algorithm.seal(key.Secret, text, append(header[:len(header):len(header)], aad...))
+	if err != nil {
+		return nil, err
+	}
+	return append(header, encrypted...), nil
+}
+
+// Decrypt decrypts the envelope with the key recorded in it.
+// It returns ErrUnknownKeyID if the Keyring does not retain the key.
+func (k *Keyring) Decrypt(envelope []byte) ([]byte, error) {
+	return k.DecryptWithAAD(envelope, nil)
+}
+
+// DecryptWithAAD is like Decrypt, but also authenticates the additional data (aad).
+func (k *Keyring) DecryptWithAAD(envelope, aad []byte) ([]byte, error) {
+	header, encrypted, keyID, err := parseEnvelope(envelope)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKeyID, keyID)
+	}
+	if alg := Algorithm(header[1]); alg != key.Algorithm {
+		return nil, fmt.Errorf("%w: algorithm %v does not match the key %q", ErrInvalidEnvelope, alg, keyID)
+	}
+	return key.Algorithm.open(key.Secret, encrypted, append(header[:len(header):len(header)], aad...))
+}
+
+// KeyID returns the key id recorded in the envelope.
+// It is useful to find the envelopes to re-encrypt after the key rotation.
+func KeyID(envelope []byte) (string, error) {
+	_, _, keyID, err := parseEnvelope(envelope)
+	return keyID, err
+}
+
+func parseEnvelope(envelope []byte) (header, encrypted []byte, keyID string, err error) {
+	if len(envelope) < 3 {
+		return nil, nil, "", fmt.Errorf("%w: too short", ErrInvalidEnvelope)
+	}
+	if v := envelope[0]; v != envelopeVersion1 {
+		return nil, nil, "", fmt.Errorf("%w: unsupported version %d", ErrInvalidEnvelope, v)
+	}
+	n := 3 + int(envelope[2])
+	if len(envelope) < n {
+		return nil, nil, "", fmt.Errorf("%w: too short", ErrInvalidEnvelope)
+	}
+	return envelope[:n], envelope[n:], string(envelope[3:n]), nil
+}
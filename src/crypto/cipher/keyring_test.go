@@ -0,0 +1,117 @@
+package cipher
+
+import (
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func randomKey(t *testing.T, n int) []byte {
+	t.Helper()
+	key := make([]byte, n)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestKeyring(t *testing.T) {
+	k1 := Key{ID: "k1", Algorithm: AlgorithmAESGCM, Secret: randomKey(t, 32)}
+	k2 := Key{ID: "k2", Algorithm: AlgorithmXChaCha20Poly1305, Secret: randomKey(t, 32)}
+
+	old, err := NewKeyring("k1", k1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope1, err := old.Encrypt([]byte("foo bar baz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// rotate to k2 retaining k1
+	rotated, err := NewKeyring("k2", k1, k2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope2, err := rotated.Encrypt([]byte("foo bar baz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range [][]byte{envelope1, envelope2} {
+		got, err := rotated.Decrypt(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g, w := string(got), "foo bar baz"; g != w {
+			t.Errorf("\ngot :%v\nwant:%v", g, w)
+		}
+	}
+	if id, _ := KeyID(envelope1); id != "k1" {
+		t.Errorf("key id got %v, want k1", id)
+	}
+	if id, _ := KeyID(envelope2); id != "k2" {
+		t.Errorf("key id got %v, want k2", id)
+	}
+
+	// k2 is unknown to the old keyring
+	if _, err := old.Decrypt(envelope2); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("err got %v, want %v", err, ErrUnknownKeyID)
+	}
+}
+
+func TestKeyringTamperedHeader(t *testing.T) {
+	secret := randomKey(t, 32)
+	// the same secret registered under two ids and algorithms
+	k, err := NewKeyring("a",
+		Key{ID: "a", Algorithm: AlgorithmXChaCha20Poly1305, Secret: secret},
+		Key{ID: "b", Algorithm: AlgorithmXChaCha20Poly1305, Secret: secret},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope, err := k.EncryptWithAAD([]byte("foo"), []byte("row:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.DecryptWithAAD(envelope, []byte("row:2")); err == nil {
+		t.Error("mismatched aad should fail to open")
+	}
+	tampered := append([]byte(nil), envelope...)
+	tampered[3] = 'b'
+	if _, err := k.DecryptWithAAD(tampered, []byte("row:1")); err == nil {
+		t.Error("tampered key id should fail to open")
+	}
+	tampered = append([]byte(nil), envelope...)
+	tampered[0] = 2
+	if _, err := k.DecryptWithAAD(tampered, []byte("row:1")); !errors.Is(err, ErrInvalidEnvelope) {
+		t.Errorf("err got %v, want %v", err, ErrInvalidEnvelope)
+	}
+	if _, err := k.Decrypt([]byte{1, 2}); !errors.Is(err, ErrInvalidEnvelope) {
+		t.Errorf("err got %v, want %v", err, ErrInvalidEnvelope)
+	}
+}
+
+func TestNewKeyringInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		active string
+		keys   []Key
+	}{
+		{name: "unknown active", active: "x", keys: []Key{{ID: "a", Algorithm: AlgorithmAESGCM, Secret: make([]byte, 16)}}},
+		{name: "empty id", active: "", keys: []Key{{ID: "", Algorithm: AlgorithmAESGCM, Secret: make([]byte, 16)}}},
+		{name: "aes key size", active: "a", keys: []Key{{ID: "a", Algorithm: AlgorithmAESGCM, Secret: make([]byte, 20)}}},
+		{name: "xchacha key size", active: "a", keys: []Key{{ID: "a", Algorithm: AlgorithmXChaCha20Poly1305, Secret: make([]byte, 16)}}},
+		{name: "unknown algorithm", active: "a", keys: []Key{{ID: "a", Algorithm: 9, Secret: make([]byte, 32)}}},
+		{name: "duplicate", active: "a", keys: []Key{
+			{ID: "a", Algorithm: AlgorithmAESGCM, Secret: make([]byte, 16)},
+			{ID: "a", Algorithm: AlgorithmAESGCM, Secret: make([]byte, 16)},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewKeyring(tt.active, tt.keys...); err == nil {
+				t.Error("err got nil, want error")
+			}
+		})
+	}
+}
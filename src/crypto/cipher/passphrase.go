@@ -0,0 +1,124 @@
+package cipher
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/kei2100/playground-go/src/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// ErrWrongPassphrase is returned by DecryptWithPassphrase when the passphrase is wrong
+// (or the encrypted is tampered with, which AEAD cannot tell apart).
+var ErrWrongPassphrase = errors.New("cipher: wrong passphrase")
+
+// ErrKDFLimitExceeded is returned when the KDF parameters recorded in the encrypted value
+// exceed the limits, before deriving the key.
+var ErrKDFLimitExceeded = errors.New("cipher: KDF params exceed the limits")
+
+// DefaultPassphraseLimits is the largest KDF cost accepted by DecryptWithPassphrase.
+// The header of the encrypted value is not authenticated until the key is derived, so the limits
+// keep an attacker-supplied value from forcing a huge allocation or computation.
+// Only Memory, Iterations and Parallelism are used.
+var DefaultPassphraseLimits = argon2.Params{
+	Memory:      argon2.MaxMemory,
+	Iterations:  argon2.MaxIterations,
+	Parallelism: 64,
+}
+
+// The result of EncryptWithPassphrase is as follows:
+//
+//	version (1 byte) || variant (1 byte) || memory (4 bytes) || iterations (4 bytes) || parallelism (1 byte) ||
+//	salt length (1 byte) || salt || nonce || ciphertext
+//
+// All integers are big endian. The header before the nonce is authenticated as the additional data.
+const (
+	passphraseVersion1    = 1
+	passphraseFixedHeader = 12
+)
+
+// EncryptWithPassphrase encrypts the text with XChaCha20-Poly1305 using the key derived from
+// the passphrase and a random salt by Argon2id with the argon2.DefaultParams.
+// The salt and the KDF parameters are recorded in the header, so that DecryptWithPassphrase
+// needs only the passphrase.
+func EncryptWithPassphrase(passphrase string, text []byte) ([]byte, error) {
+	return EncryptWithPassphraseParams(passphrase, text, argon2.DefaultParams)
+}
+
+// EncryptWithPassphraseParams is like EncryptWithPassphrase, but uses the params.
+// The params.KeyLength is ignored since XChaCha20-Poly1305 needs a 32 bytes key.
+func EncryptWithPassphraseParams(passphrase string, text []byte, params argon2.Params) ([]byte, error) {
+	params.KeyLength = chacha20poly1305.KeySize
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	if params.SaltLength > 255 {
+		return nil, fmt.Errorf("cipher: salt length %d too long", params.SaltLength)
+	}
+	salt := make([]byte, params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("cipher: creates a salt: %w", err)
+	}
+	key, err := argon2.DeriveKey([]byte(passphrase), salt, params)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(key)
+
+	header := make([]byte, 0, passphraseFixedHeader+len(salt))
+	header = append(header, passphraseVersion1, byte(params.Variant))
+	header = binary.BigEndian.AppendUint32(header, params.Memory)
+	header = binary.BigEndian.AppendUint32(header, params.Iterations)
+	header = append(header, params.Parallelism, byte(len(salt)))
+	header = append(header, salt...)
+	encrypted, err := XChaCha20Poly1305EncryptWithAAD(key, text, header)
+	if err != nil {
+		return nil, err
+	}
+	return append(header, encrypted...), nil
+}
+
+// DecryptWithPassphrase decrypts the encrypted by EncryptWithPassphrase.
+// It returns ErrWrongPassphrase if the passphrase is wrong,
+// and ErrKDFLimitExceeded if the KDF parameters exceed the DefaultPassphraseLimits.
+func DecryptWithPassphrase(passphrase string, encrypted []byte) ([]byte, error) {
+	return DecryptWithPassphraseLimits(passphrase, encrypted, DefaultPassphraseLimits)
+}
+
+// DecryptWithPassphraseLimits is like DecryptWithPassphrase, but rejects the KDF parameters exceeding
+// the Memory, Iterations or Parallelism of the limits with ErrKDFLimitExceeded.
+func DecryptWithPassphraseLimits(passphrase string, encrypted []byte, limits argon2.Params) ([]byte, error) {
+	if len(encrypted) < passphraseFixedHeader {
+		return nil, fmt.Errorf("cipher: invalid encrypted value")
+	}
+	if v := encrypted[0]; v != passphraseVersion1 {
+		return nil, fmt.Errorf("cipher: unsupported version %d", v)
+	}
+	params := argon2.Params{
+		Variant:     argon2.Variant(encrypted[1]),
+		Memory:      binary.BigEndian.Uint32(encrypted[2:6]),
+		Iterations:  binary.BigEndian.Uint32(encrypted[6:10]),
+		Parallelism: encrypted[10],
+		KeyLength:   chacha20poly1305.KeySize,
+	}
+	if params.Memory > limits.Memory || params.Iterations > limits.Iterations || params.Parallelism > limits.Parallelism {
+		return nil, fmt.Errorf("%w: m=%d,t=%d,p=%d", ErrKDFLimitExceeded, params.Memory, params.Iterations, params.Parallelism)
+	}
+	n := passphraseFixedHeader + int(encrypted[11])
+	if len(encrypted) < n {
+		return nil, fmt.Errorf("cipher: invalid encrypted value")
+	}
+	header, salt := encrypted[:n], encrypted[passphraseFixedHeader:n]
+	key, err := argon2.DeriveKey([]byte(passphrase), salt, params)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(key)
+	text, err := XChaCha20Poly1305DecryptWithAAD(key, encrypted[n:], header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrWrongPassphrase, err)
+	}
+	return text, nil
+}
@@ -0,0 +1,87 @@
+package cipher
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kei2100/playground-go/src/crypto/argon2"
+)
+
+func TestEncryptWithPassphrase(t *testing.T) {
+	// cheap parameters for testing
+	params := argon2.Params{Memory: 8 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16}
+	encrypted, err := EncryptWithPassphraseParams("correct horse", []byte("foo bar baz"), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := DecryptWithPassphrase("correct horse", encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, w := string(decrypted), "foo bar baz"; g != w {
+		t.Errorf("\ngot :%v\nwant:%v", g, w)
+	}
+
+	if _, err := DecryptWithPassphrase("battery staple", encrypted); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("err got %v, want %v", err, ErrWrongPassphrase)
+	}
+
+	// the header is authenticated
+	tampered := append([]byte(nil), encrypted...)
+	tampered[5]++ // memory
+	if _, err := DecryptWithPassphrase("correct horse", tampered); err == nil {
+		t.Error("tampered header should fail to open")
+	}
+	if _, err := DecryptWithPassphrase("correct horse", encrypted[:5]); err == nil {
+		t.Error("short encrypted should fail to open")
+	}
+}
+
+func TestDecryptWithPassphraseLimits(t *testing.T) {
+	params := argon2.Params{Memory: 8 * 1024, Iterations: 2, Parallelism: 1, SaltLength: 16}
+	encrypted, err := EncryptWithPassphraseParams("correct horse", []byte("foo"), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecryptWithPassphraseLimits("correct horse", encrypted, params); err != nil {
+		t.Errorf("err got %v, want nil", err)
+	}
+	tests := []struct {
+		name   string
+		limits argon2.Params
+	}{
+		{name: "memory", limits: argon2.Params{Memory: 8*1024 - 1, Iterations: 2, Parallelism: 1}},
+		{name: "iterations", limits: argon2.Params{Memory: 8 * 1024, Iterations: 1, Parallelism: 1}},
+		{name: "parallelism", limits: argon2.Params{Memory: 8 * 1024, Iterations: 2, Parallelism: 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecryptWithPassphraseLimits("correct horse", encrypted, tt.limits); !errors.Is(err, ErrKDFLimitExceeded) {
+				t.Errorf("err got %v, want %v", err, ErrKDFLimitExceeded)
+			}
+		})
+	}
+
+	// a crafted 12 bytes header with m=0xFFFFFFFF must be rejected before deriving the key
+	crafted := []byte{passphraseVersion1, byte(argon2.Argon2id), 0xff, 0xff, 0xff, 0xff, 0, 0, 0, 1, 1, 0}
+	if _, err := DecryptWithPassphrase("correct horse", crafted); !errors.Is(err, ErrKDFLimitExceeded) {
+		t.Errorf("err got %v, want %v", err, ErrKDFLimitExceeded)
+	}
+}
+
+func TestEncryptWithPassphraseDefaultParams(t *testing.T) {
+	if testing.Short() {
+		t.Skip("argon2 default params use 64 MiB")
+	}
+	encrypted, err := EncryptWithPassphrase("correct horse", []byte("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := DecryptWithPassphrase("correct horse", encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, w := string(decrypted), "foo"; g != w {
+		t.Errorf("\ngot :%v\nwant:%v", g, w)
+	}
+}
@@ -0,0 +1,265 @@
+package cipher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// ErrInvalidStream is returned when the encrypted stream is malformed, truncated, reordered or tampered with.
+var ErrInvalidStream = errors.New("cipher: invalid stream")
+
+// The encrypted stream is as follows:
+//
+//	version (1 byte) || algorithm (1 byte) || salt (32 bytes) || segment 0 || segment 1 || ... || last segment
+//
+// Each segment is the plaintext of streamSegmentSize bytes (the last one may be shorter or empty)
+// sealed with the AEAD, using the STREAM construction of the nonce:
+//
+//	0x00... || segment counter (4 bytes, big endian) || last segment flag (1 byte)
+//
+// so that the truncation and the reordering of the segments are detected.
+// The AEAD key is derived per stream by HKDF-SHA256 from the key and the random salt,
+// so the nonce does not need a random part.
+// https://eprint.iacr.org/2015/189.pdf
+const (
+	streamVersion1    = 1
+	streamSaltSize    = 32
+	streamHeaderSize  = 2 + streamSaltSize
+	streamSegmentSize = 64 * 1024
+)
+
+// NewEncryptWriter returns a writer that encrypts the data written to it with XChaCha20-Poly1305
+// and writes the encrypted stream to w. The key must be 32 bytes.
+// Close must be called to write the last segment; otherwise the stream is detected as truncated.
+func NewEncryptWriter(w io.Writer, key []byte) (io.WriteCloser, error) {
+	return NewEncryptWriterWithAlgorithm(w, key, AlgorithmXChaCha20Poly1305)
+}
+
+// NewEncryptWriterWithAlgorithm is like NewEncryptWriter, but uses the alg.
+// The key of AES-GCM must be either 16, 24, or 32 bytes.
+func NewEncryptWriterWithAlgorithm(w io.Writer, key []byte, alg Algorithm) (io.WriteCloser, error) {
+	if err := alg.validateKey(key); err != nil {
+		return nil, err
+	}
+	header := make([]byte, streamHeaderSize)
+	header[0], header[1] = streamVersion1, byte(alg)
+	if _, err := rand.Read(header[2:]); err != nil {
+		return nil, fmt.Errorf("cipher: creates a salt: %w", err)
+	}
+	aead, err := newStreamAEAD(key, header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, fmt.Errorf("cipher: write header: %w", err)
+	}
+	return &encryptWriter{
+		w:     w,
+		aead:  aead,
+		nonce: make([]byte, aead.NonceSize()),
+		buf:   make([]byte, 0, streamSegmentSize),
+		out:   make([]byte, 0, streamSegmentSize+aead.Overhead()),
+	}, nil
+}
+
+// NewDecryptReader returns a reader that decrypts the stream encrypted by NewEncryptWriter
+// or NewEncryptWriterWithAlgorithm. The algorithm is read from the stream header.
+// Read returns ErrInvalidStream if the stream is truncated, reordered or tampered with.
+// Note that the plaintext of a segment is returned before the following segments are verified.
+func NewDecryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	header := make([]byte, streamHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("%w: read header: %w", ErrInvalidStream, err)
+	}
+	if header[0] != streamVersion1 {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidStream, header[0])
+	}
+	if err := Algorithm(header[1]).validateKey(key); err != nil {
+		return nil, err
+	}
+	aead, err := newStreamAEAD(key, header)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{
+		r:     r,
+		aead:  aead,
+		nonce: make([]byte, aead.NonceSize()),
+		// one more byte to look ahead whether the segment is the last one
+		in:   make([]byte, streamSegmentSize+aead.Overhead()+1),
+		base: make([]byte, streamSegmentSize),
+	}, nil
+}
+
+func newStreamAEAD(key, header []byte) (cipher.AEAD, error) {
+	// the header is bound to the derived key, so that it cannot be altered
+	subkey, err := hkdf.Key(sha256.New, key, header[2:], string(header[:2])+"playground-go/cipher stream", 32)
+	if err != nil {
+		return nil, fmt.Errorf("cipher: derive a key: %w", err)
+	}
+	switch Algorithm(header[1]) {
+	case AlgorithmAESGCM:
+		block, err := aes.NewCipher(subkey)
+		if err != nil {
+			return nil, fmt.Errorf("cipher: creates an aes cipher block: %w", err)
+		}
+		aesgcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("cipher: creates an aesgcm: %w", err)
+		}
+		return aesgcm, nil
+	default:
+		aead, err := chacha20poly1305.NewX(subkey)
+		if err != nil {
+			return nil, fmt.Errorf("cipher: create aead: %w", err)
+		}
+		return aead, nil
+	}
+}
+
+// setStreamNonce sets the counter and the last segment flag to the tail of the nonce.
+func setStreamNonce(nonce []byte, counter uint32, last bool) {
+	n := len(nonce)
+	binary.BigEndian.PutUint32(nonce[n-5:n-1], counter)
+	nonce[n-1] = 0
+	if last {
+		nonce[n-1] = 1
+	}
+}
+
+type encryptWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	nonce   []byte
+	counter uint32
+	buf     []byte // plaintext of the current segment
+	out     []byte
+	err     error
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	var written int
+	for len(p) > 0 {
+		// a full segment is sealed only when more data follows,
+		// since the last segment must be sealed with the flag on Close.
+		if len(e.buf) == streamSegmentSize {
+			if err := e.seal(false); err != nil {
+				return written, err
+			}
+		}
+		n := min(len(p), streamSegmentSize-len(e.buf))
+		e.buf = append(e.buf, p[:n]...)
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close seals the last segment. It does not close the underlying writer.
+func (e *encryptWriter) Close() error {
+	if e.err != nil {
+		if errors.Is(e.err, errWriterClosed) {
+			return nil
+		}
+		return e.err
+	}
+	if err := e.seal(true); err != nil {
+		return err
+	}
+	e.err = errWriterClosed
+	return nil
+}
+
+var errWriterClosed = errors.New("cipher: write to closed writer")
+
+func (e *encryptWriter) seal(last bool) error {
+	if e.counter == math.MaxUint32 && !last {
+		e.err = errors.New("cipher: too many segments")
+		return e.err
+	}
+	setStreamNonce(e.nonce, e.counter, last)
+	e.out = e.aead.Seal(e.out[:0], e.nonce, e.buf, nil)
+	if _, err := e.w.Write(e.out); err != nil {
+		e.err = fmt.Errorf("cipher: write segment: %w", err)
+		return e.err
+	}
+	e.counter++
+	e.buf = e.buf[:0]
+	return nil
+}
+
+type decryptReader struct {
+	r       io.Reader
+	aead    cipher.AEAD
+	nonce   []byte
+	counter uint32
+	in      []byte
+	pending int    // bytes looked ahead at the head of in
+	base    []byte // full-capacity buffer that each segment is decrypted into
+	plain   []byte // unread plaintext of the current segment, a suffix of base
+	eof     bool
+	err     error
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		if d.eof {
+			return 0, io.EOF
+		}
+		if err := d.open(); err != nil {
+			d.err = err
+			return 0, err
+		}
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+func (d *decryptReader) open() error {
+	n, err := io.ReadFull(d.r, d.in[d.pending:])
+	total := d.pending + n
+	var segment []byte
+	var last bool
+	switch {
+	case err == nil:
+		segment = d.in[:total-1]
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		segment, last = d.in[:total], true
+	default:
+		return fmt.Errorf("cipher: read segment: %w", err)
+	}
+	if d.counter == math.MaxUint32 && !last {
+		return fmt.Errorf("%w: too many segments", ErrInvalidStream)
+	}
+	setStreamNonce(d.nonce, d.counter, last)
+	plain, err := d.aead.Open(d.base[:0], d.nonce, segment, nil)
+	if err != nil {
+		return fmt.Errorf("%w: decrypt segment %d: %w", ErrInvalidStream, d.counter, err)
+	}
+	d.plain = plain
+	d.counter++
+	if last {
+		d.eof = true
+		return nil
+	}
+	d.in[0] = d.in[total-1]
+	d.pending = 1
+	return nil
+}
@@ -0,0 +1,160 @@
+package cipher
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+)
+
+func encryptStream(t *testing.T, key []byte, alg Algorithm, text []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewEncryptWriterWithAlgorithm(&buf, key, alg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// write in odd-sized chunks
+	for p := text; len(p) > 0; {
+		n := min(len(p), 1000)
+		if _, err := w.Write(p[:n]); err != nil {
+			t.Fatal(err)
+		}
+		p = p[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func decryptStream(key, encrypted []byte) ([]byte, error) {
+	r, err := NewDecryptReader(bytes.NewReader(encrypted), key)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestStream(t *testing.T) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	sizes := []int{0, 1, streamSegmentSize - 1, streamSegmentSize, streamSegmentSize + 1, 3*streamSegmentSize + 5}
+	for _, alg := range []Algorithm{AlgorithmAESGCM, AlgorithmXChaCha20Poly1305} {
+		for _, size := range sizes {
+			text := make([]byte, size)
+			if _, err := rand.Read(text); err != nil {
+				t.Fatal(err)
+			}
+			encrypted := encryptStream(t, key, alg, text)
+			decrypted, err := decryptStream(key, encrypted)
+			if err != nil {
+				t.Fatalf("%v %d: %v", alg, size, err)
+			}
+			if !bytes.Equal(text, decrypted) {
+				t.Errorf("%v %d: decrypted does not match", alg, size)
+			}
+		}
+	}
+}
+
+func TestStreamDetectsTampering(t *testing.T) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	text := make([]byte, 3*streamSegmentSize+5)
+	if _, err := rand.Read(text); err != nil {
+		t.Fatal(err)
+	}
+	encrypted := encryptStream(t, key, AlgorithmXChaCha20Poly1305, text)
+	segSize := streamSegmentSize + 16
+	segment := func(i int) []byte {
+		start := streamHeaderSize + i*segSize
+		return encrypted[start:min(start+segSize, len(encrypted))]
+	}
+	concat := func(parts ...[]byte) []byte {
+		return bytes.Join(parts, nil)
+	}
+	header := encrypted[:streamHeaderSize]
+
+	tests := []struct {
+		name      string
+		encrypted []byte
+	}{
+		{name: "truncated at segment boundary", encrypted: concat(header, segment(0), segment(1))},
+		{name: "truncated in segment", encrypted: encrypted[:len(encrypted)-1]},
+		{name: "last segment removed", encrypted: concat(header, segment(0), segment(1), segment(2))},
+		{name: "reordered", encrypted: concat(header, segment(1), segment(0), segment(2), segment(3))},
+		{name: "appended", encrypted: concat(encrypted, segment(3))},
+		{name: "algorithm altered", encrypted: concat([]byte{streamVersion1, byte(AlgorithmAESGCM)}, encrypted[2:])},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decryptStream(key, tt.encrypted); !errors.Is(err, ErrInvalidStream) {
+				t.Errorf("err got %v, want %v", err, ErrInvalidStream)
+			}
+		})
+	}
+
+	t.Run("wrong key", func(t *testing.T) {
+		wrong := make([]byte, 32)
+		if _, err := decryptStream(wrong, encrypted); !errors.Is(err, ErrInvalidStream) {
+			t.Errorf("err got %v, want %v", err, ErrInvalidStream)
+		}
+	})
+}
+
+func TestStreamDecryptReuseBuffer(t *testing.T) {
+	key := make([]byte, 32)
+	encrypt := func(segments int) []byte {
+		var encrypted bytes.Buffer
+		w, err := NewEncryptWriter(&encrypted, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(make([]byte, segments*streamSegmentSize)); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return encrypted.Bytes()
+	}
+	// reading in small chunks reslices the plaintext forward, which must not cause allocations per segment
+	buf := make([]byte, 1000)
+	allocs := func(encrypted []byte) float64 {
+		return testing.AllocsPerRun(10, func() {
+			r, err := NewDecryptReader(bytes.NewReader(encrypted), key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := io.CopyBuffer(io.Discard, struct{ io.Reader }{r}, buf); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+	short, long := allocs(encrypt(1)), allocs(encrypt(9))
+	if long != short {
+		t.Errorf("allocs got %v for 9 segments, want the same as %v for 1 segment", long, short)
+	}
+}
+
+func TestStreamWriteAfterClose(t *testing.T) {
+	w, err := NewEncryptWriter(io.Discard, make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("second Close got %v, want nil", err)
+	}
+	if _, err := w.Write([]byte("foo")); err == nil {
+		t.Error("Write after Close should fail")
+	}
+}
@@ -0,0 +1,166 @@
+// Package box encrypts small messages to a Curve25519 public key using golang.org/x/crypto/nacl/box.
+//
+// SealAnonymous encrypts a message so that only the recipient can decrypt it,
+// without revealing the sender (libsodium crypto_box_seal compatible).
+// Seal additionally authenticates the sender, and prepends a random nonce to the result.
+package box
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	naclbox "golang.org/x/crypto/nacl/box"
+)
+
+// ErrOpen is returned when the message cannot be decrypted or authenticated.
+var ErrOpen = errors.New("box: failed to open")
+
+const (
+	// KeySize is the size of the public and private keys.
+	KeySize = 32
+	// NonceSize is the size of the nonce prepended by Seal.
+	NonceSize = 24
+
+	publicKeyPEMType  = "NACL BOX PUBLIC KEY"
+	privateKeyPEMType = "NACL BOX PRIVATE KEY"
+)
+
+// PublicKey is a Curve25519 public key.
+type PublicKey [KeySize]byte
+
+// PrivateKey is a Curve25519 private key.
+type PrivateKey [KeySize]byte
+
+// GenerateKey generates a new key pair.
+func GenerateKey() (*PublicKey, *PrivateKey, error) {
+	pub, priv, err := naclbox.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("box: generate key: %w", err)
+	}
+	return (*PublicKey)(pub), (*PrivateKey)(priv), nil
+}
+
+// SealAnonymous encrypts the text to the recipient. The sender is not authenticated.
+// The result is naclbox.AnonymousOverhead bytes longer than the text.
+func SealAnonymous(text []byte, recipient *PublicKey) ([]byte, error) {
+	encrypted, err := naclbox.SealAnonymous(nil, text, (*[KeySize]byte)(recipient), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("box: seal anonymous: %w", err)
+	}
+	return encrypted, nil
+}
+
+// OpenAnonymous decrypts the encrypted by SealAnonymous with the recipient key pair.
+func OpenAnonymous(encrypted []byte, pub *PublicKey, priv *PrivateKey) ([]byte, error) {
+	text, ok := naclbox.OpenAnonymous(nil, encrypted, (*[KeySize]byte)(pub), (*[KeySize]byte)(priv))
+	if !ok {
+		return nil, ErrOpen
+	}
+	return text, nil
+}
+
+// Seal encrypts and authenticates the text from the sender to the recipient.
+// A random nonce is prepended to the result.
+func Seal(text []byte, recipient *PublicKey, sender *PrivateKey) ([]byte, error) {
+	var nonce [NonceSize]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, fmt.Errorf("box: read rand: %w", err)
+	}
+	return naclbox.Seal(nonce[:], text, &nonce, (*[KeySize]byte)(recipient), (*[KeySize]byte)(sender)), nil
+}
+
+// Open decrypts the encrypted by Seal and verifies that it comes from the sender.
+func Open(encrypted []byte, sender *PublicKey, recipient *PrivateKey) ([]byte, error) {
+	if len(encrypted) < NonceSize {
+		return nil, ErrOpen
+	}
+	var nonce [NonceSize]byte
+	copy(nonce[:], encrypted[:NonceSize])
+	text, ok := naclbox.Open(nil, encrypted[NonceSize:], &nonce, (*[KeySize]byte)(sender), (*[KeySize]byte)(recipient))
+	if !ok {
+		return nil, ErrOpen
+	}
+	return text, nil
+}
+
+// MarshalText encodes the key in base64.
+func (k *PublicKey) MarshalText() ([]byte, error) {
+	return marshalKey(k[:]), nil
+}
+
+// UnmarshalText decodes the key from base64.
+func (k *PublicKey) UnmarshalText(text []byte) error {
+	return unmarshalKey(k[:], text)
+}
+
+// MarshalText encodes the key in base64.
+func (k *PrivateKey) MarshalText() ([]byte, error) {
+	return marshalKey(k[:]), nil
+}
+
+// UnmarshalText decodes the key from base64.
+func (k *PrivateKey) UnmarshalText(text []byte) error {
+	return unmarshalKey(k[:], text)
+}
+
+func marshalKey(key []byte) []byte {
+	return base64.StdEncoding.AppendEncode(nil, key)
+}
+
+func unmarshalKey(dst, text []byte) error {
+	key, err := base64.StdEncoding.AppendDecode(nil, text)
+	if err != nil {
+		return fmt.Errorf("box: decode key: %w", err)
+	}
+	if len(key) != KeySize {
+		return fmt.Errorf("box: invalid key size %d", len(key))
+	}
+	copy(dst, key)
+	return nil
+}
+
+// EncodePublicKeyPEM encodes the key in the PEM format.
+func EncodePublicKeyPEM(k *PublicKey) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: publicKeyPEMType, Bytes: k[:]})
+}
+
+// ParsePublicKeyPEM parses the key encoded by EncodePublicKeyPEM.
+func ParsePublicKeyPEM(data []byte) (*PublicKey, error) {
+	var k PublicKey
+	if err := parseKeyPEM(k[:], data, publicKeyPEMType); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// EncodePrivateKeyPEM encodes the key in the PEM format.
+func EncodePrivateKeyPEM(k *PrivateKey) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: privateKeyPEMType, Bytes: k[:]})
+}
+
+// ParsePrivateKeyPEM parses the key encoded by EncodePrivateKeyPEM.
+func ParsePrivateKeyPEM(data []byte) (*PrivateKey, error) {
+	var k PrivateKey
+	if err := parseKeyPEM(k[:], data, privateKeyPEMType); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+func parseKeyPEM(dst, data []byte, typ string) error {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return errors.New("box: no PEM data")
+	}
+	if block.Type != typ {
+		return fmt.Errorf("box: unexpected PEM type %q, want %q", block.Type, typ)
+	}
+	if len(block.Bytes) != KeySize {
+		return fmt.Errorf("box: invalid key size %d", len(block.Bytes))
+	}
+	copy(dst, block.Bytes)
+	return nil
+}
@@ -84,3 +84,104 @@ func TestBoxAnonymous(t *testing.T) {
 
 	assert.Equal(t, string(message), string(decrypted))
 }
+
+func TestSealAnonymous(t *testing.T) {
+	pub, priv, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := SealAnonymous([]byte("webhook secret"), pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, encrypted, len("webhook secret")+box.AnonymousOverhead)
+
+	decrypted, err := OpenAnonymous(encrypted, pub, priv)
+	assert.NoError(t, err)
+	assert.Equal(t, "webhook secret", string(decrypted))
+
+	otherPub, otherPriv, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = OpenAnonymous(encrypted, otherPub, otherPriv)
+	assert.ErrorIs(t, err, ErrOpen)
+}
+
+func TestSealOpen(t *testing.T) {
+	senderPub, senderPriv, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipientPub, recipientPriv, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := Seal([]byte("hello"), recipientPub, senderPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := Open(encrypted, senderPub, recipientPriv)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(decrypted))
+
+	// random nonce per message
+	encrypted2, err := Seal([]byte("hello"), recipientPub, senderPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, encrypted[:NonceSize], encrypted2[:NonceSize])
+
+	// another sender is not authenticated
+	otherPub, _, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Open(encrypted, otherPub, recipientPriv)
+	assert.ErrorIs(t, err, ErrOpen)
+	_, err = Open(encrypted[:10], senderPub, recipientPriv)
+	assert.ErrorIs(t, err, ErrOpen)
+}
+
+func TestKeySerialization(t *testing.T) {
+	pub, priv, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("base64", func(t *testing.T) {
+		text, err := pub.MarshalText()
+		assert.NoError(t, err)
+		var gotPub PublicKey
+		assert.NoError(t, gotPub.UnmarshalText(text))
+		assert.Equal(t, *pub, gotPub)
+
+		text, err = priv.MarshalText()
+		assert.NoError(t, err)
+		var gotPriv PrivateKey
+		assert.NoError(t, gotPriv.UnmarshalText(text))
+		assert.Equal(t, *priv, gotPriv)
+
+		assert.Error(t, gotPub.UnmarshalText([]byte("c2hvcnQ=")))
+		assert.Error(t, gotPub.UnmarshalText([]byte("!!!")))
+	})
+
+	t.Run("pem", func(t *testing.T) {
+		pubPEM := EncodePublicKeyPEM(pub)
+		assert.Contains(t, string(pubPEM), "-----BEGIN NACL BOX PUBLIC KEY-----")
+		gotPub, err := ParsePublicKeyPEM(pubPEM)
+		assert.NoError(t, err)
+		assert.Equal(t, pub, gotPub)
+
+		privPEM := EncodePrivateKeyPEM(priv)
+		gotPriv, err := ParsePrivateKeyPEM(privPEM)
+		assert.NoError(t, err)
+		assert.Equal(t, priv, gotPriv)
+
+		// type mismatch
+		_, err = ParsePublicKeyPEM(privPEM)
+		assert.Error(t, err)
+		_, err = ParsePublicKeyPEM([]byte("not pem"))
+		assert.Error(t, err)
+	})
+}
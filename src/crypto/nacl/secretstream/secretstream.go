@@ -0,0 +1,248 @@
+// Package secretstream encrypts a sequence of messages with a single key,
+// compatible with libsodium crypto_secretstream_xchacha20poly1305.
+// The nonces are managed internally, and the messages cannot be truncated, reordered or replayed
+// without being detected. The key is rekeyed automatically when the internal counter wraps
+// or a message is tagged with TagRekey.
+// https://doc.libsodium.org/secret-key_cryptography/secretstream
+package secretstream
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/poly1305"
+)
+
+const (
+	// KeySize is the size of the key.
+	KeySize = 32
+	// HeaderSize is the size of the header that must be sent before the messages.
+	HeaderSize = 24
+	// Overhead is the number of bytes each encrypted message is longer than the message.
+	Overhead = 1 + poly1305.TagSize
+
+	counterSize = 4
+	inonceSize  = 8
+)
+
+// Tag is the tag attached to each message.
+type Tag byte
+
+const (
+	// TagMessage is the most common tag, that doesn't add any information about the nature of the message.
+	TagMessage Tag = 0
+	// TagPush indicates that the message marks the end of a set of messages, but not the end of the stream.
+	TagPush Tag = 1
+	// TagRekey forgets the key used to encrypt this message and the previous ones, and derives a new secret key.
+	TagRekey Tag = 2
+	// TagFinal indicates that the message marks the end of the stream, and erases the secret key.
+	TagFinal = TagPush | TagRekey
+)
+
+var (
+	// ErrInvalidMessage is returned when the encrypted message cannot be authenticated.
+	ErrInvalidMessage = errors.New("secretstream: invalid message")
+	// ErrFinalized is returned when the stream has been finalized by TagFinal.
+	ErrFinalized = errors.New("secretstream: stream finalized")
+)
+
+var pad0 [16]byte
+
+type state struct {
+	k     [KeySize]byte
+	nonce [counterSize + inonceSize]byte // counter (little endian) || inonce
+	final bool
+}
+
+func (s *state) init(key, header []byte) error {
+	if len(key) != KeySize {
+		return fmt.Errorf("secretstream: invalid key size %d", len(key))
+	}
+	if len(header) != HeaderSize {
+		return fmt.Errorf("secretstream: invalid header size %d", len(header))
+	}
+	k, err := chacha20.HChaCha20(key, header[:16])
+	if err != nil {
+		return fmt.Errorf("secretstream: hchacha20: %w", err)
+	}
+	copy(s.k[:], k)
+	s.resetCounter()
+	copy(s.nonce[counterSize:], header[16:])
+	return nil
+}
+
+func (s *state) resetCounter() {
+	clear(s.nonce[:counterSize])
+	s.nonce[0] = 1
+}
+
+// rekey derives a new key and inonce from the current ones.
+func (s *state) rekey() {
+	var buf [KeySize + inonceSize]byte
+	copy(buf[:KeySize], s.k[:])
+	copy(buf[KeySize:], s.nonce[counterSize:])
+	c := s.cipher()
+	c.XORKeyStream(buf[:], buf[:])
+	copy(s.k[:], buf[:KeySize])
+	copy(s.nonce[counterSize:], buf[KeySize:])
+	clear(buf[:])
+	s.resetCounter()
+}
+
+func (s *state) cipher() *chacha20.Cipher {
+	c, err := chacha20.NewUnauthenticatedCipher(s.k[:], s.nonce[:])
+	if err != nil {
+		// the key and nonce sizes are fixed
+		panic(err)
+	}
+	return c
+}
+
+// begin returns the cipher positioned at the block counter 1, and the poly1305 MAC keyed by the block 0
+// that has already absorbed the ad.
+func (s *state) begin(ad []byte) (*chacha20.Cipher, *poly1305.MAC) {
+	c := s.cipher()
+	var polyKey [64]byte
+	c.XORKeyStream(polyKey[:], polyKey[:])
+	mac := poly1305.New((*[32]byte)(polyKey[:32]))
+	clear(polyKey[:])
+	mac.Write(ad)
+	mac.Write(pad0[:(16-len(ad)%16)%16])
+	return c, mac
+}
+
+// finish absorbs the lengths, and updates the state with the mac.
+func (s *state) finish(mac *poly1305.MAC, adLen, msgLen int) []byte {
+	// libsodium computes the padding as (0x10 - sizeof block + mlen) & 0xf,
+	// which is mlen % 16 rather than the padding to a multiple of 16.
+	mac.Write(pad0[:msgLen%16])
+	var lens [16]byte
+	binary.LittleEndian.PutUint64(lens[:8], uint64(adLen))
+	binary.LittleEndian.PutUint64(lens[8:], uint64(64+msgLen))
+	mac.Write(lens[:])
+	return mac.Sum(nil)
+}
+
+func (s *state) advance(sum []byte, tag Tag) {
+	subtle.XORBytes(s.nonce[counterSize:], s.nonce[counterSize:], sum[:inonceSize])
+	// increment the little endian counter
+	counter := binary.LittleEndian.Uint32(s.nonce[:counterSize]) + 1
+	binary.LittleEndian.PutUint32(s.nonce[:counterSize], counter)
+	if tag&TagRekey != 0 || counter == 0 {
+		s.rekey()
+	}
+	if tag == TagFinal {
+		s.final = true
+		clear(s.k[:])
+	}
+}
+
+// Encryptor encrypts the messages of a stream.
+type Encryptor struct {
+	s state
+}
+
+// NewEncryptor returns an Encryptor and the header. The header must be sent to the Decryptor.
+func NewEncryptor(key []byte) (*Encryptor, []byte, error) {
+	header := make([]byte, HeaderSize)
+	if _, err := rand.Read(header); err != nil {
+		return nil, nil, fmt.Errorf("secretstream: read rand: %w", err)
+	}
+	e, err := newEncryptor(key, header)
+	if err != nil {
+		return nil, nil, err
+	}
+	return e, header, nil
+}
+
+func newEncryptor(key, header []byte) (*Encryptor, error) {
+	var e Encryptor
+	if err := e.s.init(key, header); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// Push encrypts the msg with the tag, and authenticates the ad together.
+// The result is Overhead bytes longer than the msg.
+func (e *Encryptor) Push(msg, ad []byte, tag Tag) ([]byte, error) {
+	if e.s.final {
+		return nil, ErrFinalized
+	}
+	c, mac := e.s.begin(ad)
+	var block [64]byte
+	block[0] = byte(tag)
+	c.XORKeyStream(block[:], block[:])
+	mac.Write(block[:])
+
+	out := make([]byte, 1+len(msg), len(msg)+Overhead)
+	out[0] = block[0]
+	c.XORKeyStream(out[1:], msg)
+	mac.Write(out[1:])
+	sum := e.s.finish(mac, len(ad), len(msg))
+	out = append(out, sum...)
+	e.s.advance(sum, tag)
+	return out, nil
+}
+
+// Rekey derives a new key explicitly. The Decryptor must call Rekey at the same position of the stream.
+func (e *Encryptor) Rekey() {
+	e.s.rekey()
+}
+
+// Decryptor decrypts the messages of a stream.
+type Decryptor struct {
+	s state
+}
+
+// NewDecryptor returns a Decryptor for the stream of the header.
+func NewDecryptor(key, header []byte) (*Decryptor, error) {
+	var d Decryptor
+	if err := d.s.init(key, header); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Pull decrypts the encrypted message pushed with the ad, and returns the message and its tag.
+// It returns ErrInvalidMessage if the message is not authenticated, and the state is not changed then.
+func (d *Decryptor) Pull(encrypted, ad []byte) ([]byte, Tag, error) {
+	if d.s.final {
+		return nil, 0, ErrFinalized
+	}
+	if len(encrypted) < Overhead {
+		return nil, 0, ErrInvalidMessage
+	}
+	msgLen := len(encrypted) - Overhead
+	ciphertext, sum := encrypted[1:1+msgLen], encrypted[1+msgLen:]
+
+	c, mac := d.s.begin(ad)
+	var block [64]byte
+	block[0] = encrypted[0]
+	c.XORKeyStream(block[:], block[:])
+	tag := Tag(block[0])
+	block[0] = encrypted[0]
+	mac.Write(block[:])
+	mac.Write(ciphertext)
+	if subtle.ConstantTimeCompare(d.s.finish(mac, len(ad), msgLen), sum) != 1 {
+		return nil, 0, ErrInvalidMessage
+	}
+	msg := make([]byte, msgLen)
+	c.XORKeyStream(msg, ciphertext)
+	d.s.advance(sum, tag)
+	return msg, tag, nil
+}
+
+// Rekey derives a new key explicitly, corresponding to the Encryptor.Rekey.
+func (d *Decryptor) Rekey() {
+	d.s.rekey()
+}
+
+// Finalized reports whether the message tagged with TagFinal has been pulled.
+func (d *Decryptor) Finalized() bool {
+	return d.s.final
+}
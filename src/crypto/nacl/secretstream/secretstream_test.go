@@ -0,0 +1,185 @@
+package secretstream
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// libsodiumVectors are generated by libsodium 1.0.18 crypto_secretstream_xchacha20poly1305_push
+// with the key 000102...1f. A nil msg means crypto_secretstream_xchacha20poly1305_rekey.
+var libsodiumVectors = struct {
+	key      string
+	header   string
+	messages []struct {
+		msg       []byte
+		ad        []byte
+		tag       Tag
+		encrypted string
+	}
+}{
+	key:    "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+	header: "cee07330203a7b35d3bb17c1007a012a6ce3f0ba4703b019",
+	messages: []struct {
+		msg       []byte
+		ad        []byte
+		tag       Tag
+		encrypted string
+	}{
+		{msg: []byte("Arbitrary data to encrypt"), tag: TagMessage, encrypted: "27c5fcef78c14879ffc5012faca0f0cb427845ce3cea64c6d964fd49f6e1aa004514a5f3c759f8dbee03"},
+		{msg: []byte("split into"), ad: []byte("ad"), tag: TagPush, encrypted: "769875bf16078015675cdd86233c47f28cf8dfb9d31e4e43e3f912"},
+		{msg: []byte(""), tag: TagRekey, encrypted: "1ccd6e877d3dd74bc8350e53567e1f9c53"},
+		{msg: []byte("three messages after rekey"), tag: TagMessage, encrypted: "56076d87f64fd53ee7301dbe1ca111b48935e4cb6ad3ce74245c514f6f8957bab093a669feb38b4e68e87d"},
+		{msg: nil},
+		{msg: []byte(strings.Repeat("x", 100)), ad: []byte("associated"), tag: TagMessage, encrypted: "342684a090fa3a87798f905680c307f154070f016e2e9bd0b3cfb17ffde1a54042315cfa38943ce6848fc29938b3593c6cb85af37bdd159cbb67d213d0fbb8ca286b498153b846fdeadd7b544e360b73a75ed7f7cfbd54cd0f30aceb53e5fe951fa9837ed1dada0f8fa6745a7ec0651abacffa4c17"},
+		{msg: []byte("final"), tag: TagFinal, encrypted: "1fb5f4a47f58a7f3ccf1a87efecadaf696e08eb87efd"},
+	},
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestLibsodiumVectors(t *testing.T) {
+	key := mustHex(t, libsodiumVectors.key)
+	header := mustHex(t, libsodiumVectors.header)
+
+	t.Run("push", func(t *testing.T) {
+		e, err := newEncryptor(key, header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, m := range libsodiumVectors.messages {
+			if m.msg == nil {
+				e.Rekey()
+				continue
+			}
+			got, err := e.Push(m.msg, m.ad, m.tag)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if w := mustHex(t, m.encrypted); !bytes.Equal(got, w) {
+				t.Errorf("message %d:\ngot :%x\nwant:%x", i, got, w)
+			}
+		}
+		_, err = e.Push([]byte("after final"), nil, TagMessage)
+		assert.ErrorIs(t, err, ErrFinalized)
+	})
+
+	t.Run("pull", func(t *testing.T) {
+		d, err := NewDecryptor(key, header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, m := range libsodiumVectors.messages {
+			if m.msg == nil {
+				d.Rekey()
+				continue
+			}
+			got, tag, err := d.Pull(mustHex(t, m.encrypted), m.ad)
+			if err != nil {
+				t.Fatalf("message %d: %v", i, err)
+			}
+			assert.Equal(t, string(m.msg), string(got), "message %d", i)
+			assert.Equal(t, m.tag, tag, "message %d", i)
+		}
+		assert.True(t, d.Finalized())
+	})
+}
+
+func TestDetectsTampering(t *testing.T) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	e, header, err := NewEncryptor(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var encrypted [][]byte
+	for _, m := range []string{"a", "b", "c"} {
+		c, err := e.Push([]byte(m), nil, TagMessage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encrypted = append(encrypted, c)
+	}
+
+	tests := []struct {
+		name  string
+		order []int
+		ad    []byte
+	}{
+		{name: "reordered", order: []int{1}},
+		{name: "replayed", order: []int{0, 0}},
+		{name: "dropped", order: []int{0, 2}},
+		{name: "mismatched ad", order: []int{0}, ad: []byte("ad")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := NewDecryptor(key, header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var lastErr error
+			for _, i := range tt.order {
+				if _, _, lastErr = d.Pull(encrypted[i], tt.ad); lastErr != nil {
+					break
+				}
+			}
+			assert.True(t, errors.Is(lastErr, ErrInvalidMessage), "err got %v", lastErr)
+		})
+	}
+
+	t.Run("state is kept after an invalid message", func(t *testing.T) {
+		d, err := NewDecryptor(key, header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = d.Pull(encrypted[1], nil)
+		assert.ErrorIs(t, err, ErrInvalidMessage)
+		got, _, err := d.Pull(encrypted[0], nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "a", string(got))
+	})
+}
+
+func TestAutomaticRekeyOnCounterWrap(t *testing.T) {
+	key := make([]byte, KeySize)
+	e, header, err := NewEncryptor(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := NewDecryptor(key, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// jump to the end of the counter
+	for _, s := range []*state{&e.s, &d.s} {
+		copy(s.nonce[:counterSize], []byte{0xff, 0xff, 0xff, 0xff})
+	}
+	before := e.s.k
+	for _, m := range []string{"wrap", "after wrap"} {
+		c, err := e.Push([]byte(m), nil, TagMessage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _, err := d.Pull(c, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, m, string(got))
+	}
+	assert.NotEqual(t, before, e.s.k)
+	assert.Equal(t, e.s, d.s)
+}
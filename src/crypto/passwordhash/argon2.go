@@ -0,0 +1,37 @@
+package passwordhash
+
+import (
+	"errors"
+
+	"github.com/kei2100/playground-go/src/crypto/argon2"
+)
+
+// Argon2 is the Algorithm of the argon2 package.
+type Argon2 struct {
+	Params argon2.Params
+}
+
+// NewArgon2 returns an Argon2 using the params.
+func NewArgon2(params argon2.Params) *Argon2 {
+	return &Argon2{Params: params}
+}
+
+func (a *Argon2) IDs() []string {
+	return []string{argon2.Argon2id.String(), argon2.Argon2i.String()}
+}
+
+func (a *Argon2) Hash(password string) (string, error) {
+	return argon2.GenerateFromPasswordWithParams(password, a.Params)
+}
+
+func (a *Argon2) Verify(encodedHash, password string) error {
+	err := argon2.CompareHashAndPassword(encodedHash, password)
+	if errors.Is(err, argon2.ErrMismatchedHashAndPassword) {
+		return ErrMismatchedHashAndPassword
+	}
+	return err
+}
+
+func (a *Argon2) NeedsRehash(encodedHash string) (bool, error) {
+	return argon2.NeedsRehash(encodedHash, a.Params)
+}
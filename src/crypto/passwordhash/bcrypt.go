@@ -0,0 +1,51 @@
+package passwordhash
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Bcrypt is the Algorithm of bcrypt ($2a$, $2b$ and $2y$).
+// Note that bcrypt uses only the first 72 bytes of the password,
+// and Hash returns an error for the longer password.
+type Bcrypt struct {
+	Cost int
+}
+
+// NewBcrypt returns a Bcrypt using the cost.
+func NewBcrypt(cost int) *Bcrypt {
+	return &Bcrypt{Cost: cost}
+}
+
+func (b *Bcrypt) IDs() []string {
+	return []string{"2a", "2b", "2y"}
+}
+
+func (b *Bcrypt) Hash(password string) (string, error) {
+	h, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	if err != nil {
+		return "", fmt.Errorf("passwordhash: bcrypt: %w", err)
+	}
+	return string(h), nil
+}
+
+func (b *Bcrypt) Verify(encodedHash, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrMismatchedHashAndPassword
+	}
+	if err != nil {
+		return fmt.Errorf("passwordhash: bcrypt: %w", err)
+	}
+	return nil
+}
+
+func (b *Bcrypt) NeedsRehash(encodedHash string) (bool, error) {
+	cost, err := bcrypt.Cost([]byte(encodedHash))
+	if err != nil {
+		return false, fmt.Errorf("passwordhash: bcrypt: %w", err)
+	}
+	return cost != b.Cost, nil
+}
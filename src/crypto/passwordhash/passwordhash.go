@@ -0,0 +1,122 @@
+// Package passwordhash verifies password hashes produced by several algorithms
+// (argon2, bcrypt, scrypt and PBKDF2) and hashes new passwords with a preferred one.
+package passwordhash
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrMismatchedHashAndPassword is returned when the password does not match the hash.
+	ErrMismatchedHashAndPassword = errors.New("passwordhash: mismatched hash and password")
+	// ErrUnknownAlgorithm is returned when no registered Algorithm handles the hash.
+	ErrUnknownAlgorithm = errors.New("passwordhash: unknown algorithm")
+	// ErrCostExceeded is returned when the cost parameters of the hash exceed the limits of the algorithm,
+	// so that a malformed hash in the database cannot force a huge allocation or computation.
+	ErrCostExceeded = errors.New("passwordhash: cost params exceed the limits")
+)
+
+// Algorithm is a password hashing algorithm.
+type Algorithm interface {
+	// IDs returns the identifiers between the first and the second '$' of the hashes
+	// the Algorithm handles, e.g. "2a" for bcrypt or "argon2id" for argon2.
+	IDs() []string
+	// Hash returns the encoded hash of the password.
+	Hash(password string) (string, error)
+	// Verify compares the encoded hash with the password.
+	// It returns ErrMismatchedHashAndPassword if they do not match.
+	Verify(encodedHash, password string) error
+	// NeedsRehash reports whether the encoded hash was generated with parameters
+	// other than the configured ones.
+	NeedsRehash(encodedHash string) (bool, error)
+}
+
+// Registry dispatches the hashes to the Algorithm by the identifier prefix.
+type Registry struct {
+	preferred  Algorithm
+	algorithms map[string]Algorithm
+}
+
+// New returns a Registry that hashes new passwords with the preferred
+// and verifies the hashes of the preferred and the others.
+func New(preferred Algorithm, others ...Algorithm) *Registry {
+	r := &Registry{preferred: preferred, algorithms: make(map[string]Algorithm)}
+	for _, a := range others {
+		r.register(a)
+	}
+	// the preferred wins when the identifiers collide
+	r.register(preferred)
+	return r
+}
+
+func (r *Registry) register(a Algorithm) {
+	for _, id := range a.IDs() {
+		r.algorithms[id] = a
+	}
+}
+
+// Hash returns the encoded hash of the password using the preferred Algorithm.
+func (r *Registry) Hash(password string) (string, error) {
+	return r.preferred.Hash(password)
+}
+
+// Verify compares the encoded hash with the password using the Algorithm that handles the hash.
+// It returns ErrMismatchedHashAndPassword if they do not match.
+func (r *Registry) Verify(encodedHash, password string) error {
+	a, err := r.lookup(encodedHash)
+	if err != nil {
+		return err
+	}
+	return a.Verify(encodedHash, password)
+}
+
+// NeedsRehash reports whether the encoded hash should be replaced by a hash of the preferred Algorithm.
+// It is true when the hash is produced by another Algorithm, or by the preferred with stale parameters.
+func (r *Registry) NeedsRehash(encodedHash string) (bool, error) {
+	a, err := r.lookup(encodedHash)
+	if err != nil {
+		return false, err
+	}
+	if a != r.preferred {
+		return true, nil
+	}
+	return a.NeedsRehash(encodedHash)
+}
+
+// VerifyAndUpgrade verifies the encoded hash with the password like Verify.
+// When the password matches and the hash needs rehash, it returns a new encoded hash
+// generated by the preferred Algorithm. Otherwise, newHash is empty.
+func (r *Registry) VerifyAndUpgrade(encodedHash, password string) (newHash string, err error) {
+	if err := r.Verify(encodedHash, password); err != nil {
+		return "", err
+	}
+	needs, err := r.NeedsRehash(encodedHash)
+	if err != nil {
+		return "", err
+	}
+	if !needs {
+		return "", nil
+	}
+	return r.Hash(password)
+}
+
+func (r *Registry) lookup(encodedHash string) (Algorithm, error) {
+	id := hashID(encodedHash)
+	a, ok := r.algorithms[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownAlgorithm, id)
+	}
+	return a, nil
+}
+
+// hashID returns the identifier between the first and the second '$' of the encoded hash.
+func hashID(encodedHash string) string {
+	s, ok := strings.CutPrefix(encodedHash, "$")
+	if !ok {
+		return ""
+	}
+	id, _, _ := strings.Cut(s, "$")
+	return id
+}
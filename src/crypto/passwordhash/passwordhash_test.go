@@ -0,0 +1,173 @@
+package passwordhash
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kei2100/playground-go/src/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cheap parameters for testing
+var (
+	testArgon2 = NewArgon2(argon2.Params{Memory: 8 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+	testBcrypt = NewBcrypt(bcrypt.MinCost)
+	testScrypt = &Scrypt{LogN: 10, R: 8, P: 1, SaltLength: 16, KeyLength: 32}
+	testPBKDF2 = &PBKDF2SHA256{Iterations: 1000, SaltLength: 16, KeyLength: 32}
+)
+
+func TestAlgorithms(t *testing.T) {
+	tests := []struct {
+		name   string
+		alg    Algorithm
+		prefix string
+	}{
+		{name: "argon2", alg: testArgon2, prefix: "$argon2id$"},
+		{name: "bcrypt", alg: testBcrypt, prefix: "$2a$"},
+		{name: "scrypt", alg: testScrypt, prefix: "$scrypt$ln=10,r=8,p=1$"},
+		{name: "pbkdf2", alg: testPBKDF2, prefix: "$pbkdf2-sha256$i=1000,l=32$"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := tt.alg.Hash("foo")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(h, tt.prefix) {
+				t.Errorf("hash got %v, want prefix %v", h, tt.prefix)
+			}
+			if err := tt.alg.Verify(h, "foo"); err != nil {
+				t.Errorf("err got %v, want nil", err)
+			}
+			if err := tt.alg.Verify(h, "bar"); !errors.Is(err, ErrMismatchedHashAndPassword) {
+				t.Errorf("err got %v, want %v", err, ErrMismatchedHashAndPassword)
+			}
+			needs, err := tt.alg.NeedsRehash(h)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if needs {
+				t.Errorf("needs rehash got true, want false")
+			}
+		})
+	}
+}
+
+func TestKnownHashes(t *testing.T) {
+	tests := []struct {
+		name string
+		alg  Algorithm
+		hash string
+	}{
+		// echo -n password | argon2 somesalt -id -t 2 -m 16 -p 1 -l 32
+		{name: "argon2id", alg: testArgon2, hash: "$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"},
+		// python -c 'import hashlib,base64; print(base64.b64encode(hashlib.scrypt(b"password", salt=b"somesalt", n=1024, r=8, p=1, dklen=32)))'
+		{name: "scrypt", alg: testScrypt, hash: "$scrypt$ln=10,r=8,p=1$c29tZXNhbHQ$wdXoWEig5T693O7BJbufEPRk+qarG40BYOh1xe9tMAc"},
+		// python -c 'import hashlib,base64; print(base64.b64encode(hashlib.pbkdf2_hmac("sha256", b"password", b"somesalt", 1000)))'
+		{name: "pbkdf2-sha256", alg: testPBKDF2, hash: "$pbkdf2-sha256$i=1000,l=32$c29tZXNhbHQ$j4Aa14inUtOh7Sg/D7hH54ohymuHNQD4+ccfhepGWAY"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.alg.Verify(tt.hash, "password"); err != nil {
+				t.Errorf("err got %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestCostExceeded(t *testing.T) {
+	const saltAndHash = "$c29tZXNhbHQ$wdXoWEig5T693O7BJbufEPRk+qarG40BYOh1xe9tMAc"
+	tests := []struct {
+		name string
+		alg  Algorithm
+		hash string
+	}{
+		{name: "scrypt ln", alg: testScrypt, hash: "$scrypt$ln=21,r=1,p=1" + saltAndHash},
+		{name: "scrypt ln=63", alg: testScrypt, hash: "$scrypt$ln=63,r=8,p=1" + saltAndHash},
+		{name: "scrypt r*p", alg: testScrypt, hash: "$scrypt$ln=10,r=8,p=5" + saltAndHash},
+		{name: "scrypt huge p", alg: testScrypt, hash: "$scrypt$ln=10,r=1,p=1000000000" + saltAndHash},
+		{name: "scrypt memory", alg: testScrypt, hash: "$scrypt$ln=20,r=16,p=1" + saltAndHash},
+		{name: "pbkdf2 i", alg: testPBKDF2, hash: "$pbkdf2-sha256$i=20000000,l=32" + saltAndHash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.alg.Verify(tt.hash, "password"); !errors.Is(err, ErrCostExceeded) {
+				t.Errorf("Verify err got %v, want %v", err, ErrCostExceeded)
+			}
+			if _, err := tt.alg.NeedsRehash(tt.hash); !errors.Is(err, ErrCostExceeded) {
+				t.Errorf("NeedsRehash err got %v, want %v", err, ErrCostExceeded)
+			}
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := New(testArgon2, testBcrypt, testScrypt, testPBKDF2)
+
+	var hashes []string
+	for _, a := range []Algorithm{testArgon2, testBcrypt, testScrypt, testPBKDF2} {
+		h, err := a.Hash("foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, h)
+	}
+	for _, h := range hashes {
+		if err := r.Verify(h, "foo"); err != nil {
+			t.Errorf("%s: err got %v, want nil", h, err)
+		}
+		if err := r.Verify(h, "bar"); !errors.Is(err, ErrMismatchedHashAndPassword) {
+			t.Errorf("%s: err got %v, want %v", h, err, ErrMismatchedHashAndPassword)
+		}
+	}
+
+	h, err := r.Hash("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(h, "$argon2id$") {
+		t.Errorf("hash got %v, want preferred argon2id", h)
+	}
+
+	if err := r.Verify("$md5$xxx", "foo"); !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Errorf("err got %v, want %v", err, ErrUnknownAlgorithm)
+	}
+}
+
+func TestRegistryVerifyAndUpgrade(t *testing.T) {
+	r := New(testArgon2, testBcrypt)
+
+	legacy, err := testBcrypt.Hash("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.VerifyAndUpgrade(legacy, "bar"); !errors.Is(err, ErrMismatchedHashAndPassword) {
+		t.Errorf("err got %v, want %v", err, ErrMismatchedHashAndPassword)
+	}
+	upgraded, err := r.VerifyAndUpgrade(legacy, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(upgraded, "$argon2id$") {
+		t.Fatalf("upgraded got %v, want argon2id", upgraded)
+	}
+	same, err := r.VerifyAndUpgrade(upgraded, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same != "" {
+		t.Errorf("newHash got %v, want empty", same)
+	}
+
+	// raising the cost of the preferred
+	stronger := NewArgon2(argon2.Params{Memory: 16 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+	r = New(stronger, testBcrypt)
+	rehashed, err := r.VerifyAndUpgrade(upgraded, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(rehashed, "$argon2id$v=19$m=16384,") {
+		t.Errorf("rehashed got %v", rehashed)
+	}
+}
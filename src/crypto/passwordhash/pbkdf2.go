@@ -0,0 +1,104 @@
+package passwordhash
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"strconv"
+
+	"github.com/kei2100/playground-go/src/crypto/argon2"
+)
+
+// PBKDF2SHA256 is the Algorithm of PBKDF2-HMAC-SHA256 encoded in the PHC string format.
+//
+//	$pbkdf2-sha256$i=<iterations>,l=<key length>$<salt>$<hash>
+type PBKDF2SHA256 struct {
+	Iterations int
+	SaltLength int
+	KeyLength  int
+}
+
+// MaxPBKDF2Iterations is the largest iterations accepted from an encoded hash.
+const MaxPBKDF2Iterations = 5_000_000
+
+// NewPBKDF2SHA256 returns a PBKDF2SHA256 with the iterations recommended by OWASP (600,000).
+// https://cheatsheetseries.owasp.org/cheatsheets/Password_Storage_Cheat_Sheet.html#pbkdf2
+func NewPBKDF2SHA256() *PBKDF2SHA256 {
+	return &PBKDF2SHA256{Iterations: 600_000, SaltLength: 16, KeyLength: 32}
+}
+
+func (p *PBKDF2SHA256) IDs() []string {
+	return []string{"pbkdf2-sha256"}
+}
+
+func (p *PBKDF2SHA256) Hash(password string) (string, error) {
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("passwordhash: pbkdf2: generate salt: %w", err)
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, p.Iterations, p.KeyLength)
+	if err != nil {
+		return "", fmt.Errorf("passwordhash: pbkdf2: %w", err)
+	}
+	h := argon2.PHCHash{
+		ID: "pbkdf2-sha256",
+		Params: []argon2.PHCParam{
+			{Name: "i", Value: strconv.Itoa(p.Iterations)},
+			{Name: "l", Value: strconv.Itoa(p.KeyLength)},
+		},
+		Salt: salt,
+		Hash: key,
+	}
+	return h.String(), nil
+}
+
+func (p *PBKDF2SHA256) Verify(encodedHash, password string) error {
+	got, h, err := p.decode(encodedHash)
+	if err != nil {
+		return err
+	}
+	key, err := pbkdf2.Key(sha256.New, password, h.Salt, got.Iterations, got.KeyLength)
+	if err != nil {
+		return fmt.Errorf("passwordhash: pbkdf2: %w", err)
+	}
+	if subtle.ConstantTimeCompare(h.Hash, key) != 1 {
+		return ErrMismatchedHashAndPassword
+	}
+	return nil
+}
+
+func (p *PBKDF2SHA256) NeedsRehash(encodedHash string) (bool, error) {
+	got, _, err := p.decode(encodedHash)
+	if err != nil {
+		return false, err
+	}
+	return got.Iterations != p.Iterations || got.KeyLength != p.KeyLength, nil
+}
+
+func (p *PBKDF2SHA256) decode(encodedHash string) (*PBKDF2SHA256, *argon2.PHCHash, error) {
+	h, err := argon2.ParsePHC(encodedHash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("passwordhash: pbkdf2: %w", err)
+	}
+	if h.ID != "pbkdf2-sha256" || len(h.Salt) == 0 || len(h.Hash) == 0 {
+		return nil, nil, fmt.Errorf("passwordhash: pbkdf2: unexpected hash format")
+	}
+	var got PBKDF2SHA256
+	if got.Iterations, err = intParam(h, "i"); err != nil {
+		return nil, nil, fmt.Errorf("passwordhash: pbkdf2: %w", err)
+	}
+	if got.Iterations > MaxPBKDF2Iterations {
+		return nil, nil, fmt.Errorf("%w: pbkdf2: i=%d", ErrCostExceeded, got.Iterations)
+	}
+	got.SaltLength, got.KeyLength = len(h.Salt), len(h.Hash)
+	// l is optional, but must match the hash length if present
+	if _, ok := h.Param("l"); ok {
+		l, err := intParam(h, "l")
+		if err != nil || l != got.KeyLength {
+			return nil, nil, fmt.Errorf("passwordhash: pbkdf2: invalid key length")
+		}
+	}
+	return &got, h, nil
+}
@@ -0,0 +1,122 @@
+package passwordhash
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"strconv"
+
+	"github.com/kei2100/playground-go/src/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Scrypt is the Algorithm of scrypt encoded in the PHC string format.
+//
+//	$scrypt$ln=<log2(N)>,r=<r>,p=<p>$<salt>$<hash>
+type Scrypt struct {
+	// LogN is the log2 of the CPU/memory cost parameter N.
+	LogN       int
+	R          int
+	P          int
+	SaltLength int
+	KeyLength  int
+}
+
+// The limits of the cost parameters accepted from an encoded hash.
+const (
+	// MaxScryptLogN is the largest ln (N=2^20).
+	MaxScryptLogN = 20
+	// MaxScryptRP is the largest r*p.
+	MaxScryptRP = 32
+	// MaxScryptMemory is the largest memory size 128*r*N in bytes (1 GiB).
+	MaxScryptMemory = 1 << 30
+)
+
+// NewScrypt returns a Scrypt with the parameters recommended by the scrypt package (N=32768, r=8, p=1).
+func NewScrypt() *Scrypt {
+	return &Scrypt{LogN: 15, R: 8, P: 1, SaltLength: 16, KeyLength: 32}
+}
+
+func (s *Scrypt) IDs() []string {
+	return []string{"scrypt"}
+}
+
+func (s *Scrypt) Hash(password string) (string, error) {
+	salt := make([]byte, s.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("passwordhash: scrypt: generate salt: %w", err)
+	}
+	key, err := scrypt.Key([]byte(password), salt, 1<<s.LogN, s.R, s.P, s.KeyLength)
+	if err != nil {
+		return "", fmt.Errorf("passwordhash: scrypt: %w", err)
+	}
+	h := argon2.PHCHash{
+		ID: "scrypt",
+		Params: []argon2.PHCParam{
+			{Name: "ln", Value: strconv.Itoa(s.LogN)},
+			{Name: "r", Value: strconv.Itoa(s.R)},
+			{Name: "p", Value: strconv.Itoa(s.P)},
+		},
+		Salt: salt,
+		Hash: key,
+	}
+	return h.String(), nil
+}
+
+func (s *Scrypt) Verify(encodedHash, password string) error {
+	got, h, err := s.decode(encodedHash)
+	if err != nil {
+		return err
+	}
+	key, err := scrypt.Key([]byte(password), h.Salt, 1<<got.LogN, got.R, got.P, got.KeyLength)
+	if err != nil {
+		return fmt.Errorf("passwordhash: scrypt: %w", err)
+	}
+	if subtle.ConstantTimeCompare(h.Hash, key) != 1 {
+		return ErrMismatchedHashAndPassword
+	}
+	return nil
+}
+
+func (s *Scrypt) NeedsRehash(encodedHash string) (bool, error) {
+	got, _, err := s.decode(encodedHash)
+	if err != nil {
+		return false, err
+	}
+	return got.LogN != s.LogN || got.R != s.R || got.P != s.P || got.KeyLength != s.KeyLength, nil
+}
+
+func (s *Scrypt) decode(encodedHash string) (*Scrypt, *argon2.PHCHash, error) {
+	h, err := argon2.ParsePHC(encodedHash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("passwordhash: scrypt: %w", err)
+	}
+	if h.ID != "scrypt" || len(h.Salt) == 0 || len(h.Hash) == 0 {
+		return nil, nil, fmt.Errorf("passwordhash: scrypt: unexpected hash format")
+	}
+	var got Scrypt
+	for name, dst := range map[string]*int{"ln": &got.LogN, "r": &got.R, "p": &got.P} {
+		if *dst, err = intParam(h, name); err != nil {
+			return nil, nil, fmt.Errorf("passwordhash: scrypt: %w", err)
+		}
+	}
+	if got.LogN > MaxScryptLogN || got.R > MaxScryptRP || got.P > MaxScryptRP ||
+		got.R*got.P > MaxScryptRP || 128*got.R<<got.LogN > MaxScryptMemory {
+		return nil, nil, fmt.Errorf("%w: scrypt: ln=%d,r=%d,p=%d", ErrCostExceeded, got.LogN, got.R, got.P)
+	}
+	got.SaltLength, got.KeyLength = len(h.Salt), len(h.Hash)
+	return &got, h, nil
+}
+
+// intParam returns the positive integer parameter named name of the h.
+func intParam(h *argon2.PHCHash, name string) (int, error) {
+	v, ok := h.Param(name)
+	if !ok {
+		return 0, fmt.Errorf("missing param %s", name)
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid param %s=%s", name, v)
+	}
+	return n, nil
+}
@@ -0,0 +1,106 @@
+// Package securecompare provides constant-time comparison of secrets such as API keys and CSRF tokens.
+//
+// subtle.ConstantTimeCompare returns immediately when the lengths differ, so it leaks the length of the secret.
+// The functions of this package compare the HMAC-SHA256 of the inputs under a random key instead,
+// so the comparison time depends neither on where the inputs differ nor on whether their lengths match
+// (within the same 64 bytes block count, see Equal).
+package securecompare
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+)
+
+// macKey is the per-process random key. It only needs to be unpredictable to the attacker, not persistent.
+var macKey = func() []byte {
+	key := make([]byte, sha256.Size)
+	rand.Read(key)
+	return key
+}()
+
+// mac returns the HMAC of the s padded to a multiple of the SHA-256 block size,
+// so that the hashing time depends only on the number of blocks, not on the exact length.
+// The length is prepended to keep the padding unambiguous.
+func mac(s []byte) [sha256.Size]byte {
+	n := (8 + len(s) + sha256.BlockSize - 1) &^ (sha256.BlockSize - 1)
+	var stack [2 * sha256.BlockSize]byte
+	var buf []byte
+	if n <= len(stack) {
+		buf = stack[:n]
+	} else {
+		buf = make([]byte, n)
+	}
+	binary.BigEndian.PutUint64(buf, uint64(len(s)))
+	copy(buf[8:], s)
+
+	h := hmac.New(sha256.New, macKey)
+	h.Write(buf)
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// Equal reports whether a and b are equal without leaking their contents or lengths through timing.
+// The inputs are padded to a multiple of 64 bytes before hashing, so the time depends only on
+// how many 64 bytes blocks they occupy (one for the inputs up to 56 bytes, which covers typical tokens).
+func Equal(a, b string) bool {
+	return EqualBytes([]byte(a), []byte(b))
+}
+
+// EqualBytes is the []byte version of Equal.
+func EqualBytes(a, b []byte) bool {
+	ma, mb := mac(a), mac(b)
+	return subtle.ConstantTimeCompare(ma[:], mb[:]) == 1
+}
+
+// Set is a set of secret tokens that can be looked up in constant time.
+// It compares the token with every candidate, so the lookup time does not depend on
+// which candidate matches or whether any candidate matches.
+// Set keeps only the HMACs of the candidates, not the candidates themselves.
+// A Set is safe for concurrent use.
+type Set struct {
+	macs [][sha256.Size]byte
+}
+
+// NewSet returns a Set of the candidates.
+func NewSet(candidates ...string) *Set {
+	s := &Set{macs: make([][sha256.Size]byte, len(candidates))}
+	for i, c := range candidates {
+		s.macs[i] = mac([]byte(c))
+	}
+	return s
+}
+
+// Len returns the number of candidates.
+func (s *Set) Len() int {
+	return len(s.macs)
+}
+
+// Contains reports whether the token is one of the candidates.
+func (s *Set) Contains(token string) bool {
+	_, ok := s.Index(token)
+	return ok
+}
+
+// Index returns the index of the token in the candidates passed to NewSet.
+// If the token appears more than once, the last index is returned.
+// ok is false if the token is not found.
+func (s *Set) Index(token string) (index int, ok bool) {
+	m := mac([]byte(token))
+	found := 0
+	for i := range s.macs {
+		eq := subtle.ConstantTimeCompare(m[:], s.macs[i][:])
+		index = subtle.ConstantTimeSelect(eq, i, index)
+		found |= eq
+	}
+	return index, found == 1
+}
+
+// Lookup returns the index of the token in the candidates in constant time like Set.Index.
+// Use NewSet to avoid computing the HMACs of the candidates on every lookup.
+func Lookup(token string, candidates []string) (index int, ok bool) {
+	return NewSet(candidates...).Index(token)
+}
@@ -0,0 +1,64 @@
+package securecompare
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "token", b: "token", want: true},
+		{a: "", b: "", want: true},
+		{a: "token", b: "TOKEN", want: false},
+		{a: "token", b: "token2", want: false},
+		{a: "token", b: "", want: false},
+		// the zero padding must not make these equal
+		{a: "token", b: "token\x00", want: false},
+		{a: strings.Repeat("x", 100), b: strings.Repeat("x", 100), want: true},
+		{a: strings.Repeat("x", 100), b: strings.Repeat("x", 99) + "y", want: false},
+	}
+	for _, tt := range tests {
+		if got := Equal(tt.a, tt.b); got != tt.want {
+			t.Errorf("Equal(%q, %q) got %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+		if got := EqualBytes([]byte(tt.a), []byte(tt.b)); got != tt.want {
+			t.Errorf("EqualBytes(%q, %q) got %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	s := NewSet("key-a", "key-b", "key-c", "key-b")
+	if s.Len() != 4 {
+		t.Errorf("Len got %v, want 4", s.Len())
+	}
+	tests := []struct {
+		token   string
+		wantIdx int
+		wantOK  bool
+	}{
+		{token: "key-a", wantIdx: 0, wantOK: true},
+		{token: "key-c", wantIdx: 2, wantOK: true},
+		{token: "key-b", wantIdx: 3, wantOK: true},
+		{token: "key-d", wantIdx: 0, wantOK: false},
+		{token: "", wantIdx: 0, wantOK: false},
+	}
+	for _, tt := range tests {
+		idx, ok := s.Index(tt.token)
+		if idx != tt.wantIdx || ok != tt.wantOK {
+			t.Errorf("Index(%q) got (%v, %v), want (%v, %v)", tt.token, idx, ok, tt.wantIdx, tt.wantOK)
+		}
+		if got := s.Contains(tt.token); got != tt.wantOK {
+			t.Errorf("Contains(%q) got %v, want %v", tt.token, got, tt.wantOK)
+		}
+	}
+	if _, ok := NewSet().Index("key-a"); ok {
+		t.Error("empty set contains key-a")
+	}
+	if idx, ok := Lookup("y", []string{"x", "y"}); idx != 1 || !ok {
+		t.Errorf("Lookup got (%v, %v), want (1, true)", idx, ok)
+	}
+}
@@ -0,0 +1,172 @@
+package securecompare
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"math"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// timingT measures f for two classes of inputs in random order and returns Welch's t statistic
+// of the two distributions of the durations, like dudect (https://eprint.iacr.org/2016/1123).
+// f is called with the class 0 or 1 and must do the same work except for the inputs.
+// A |t| greater than tThreshold suggests that the duration depends on the class.
+func timingT(n, batch int, f func(class int)) float64 {
+	type sample struct {
+		class int
+		d     time.Duration
+	}
+	samples := make([]sample, n)
+	for i := range samples {
+		class := rand.IntN(2)
+		start := time.Now()
+		// a single call is too short for the resolution of the clock
+		for range batch {
+			f(class)
+		}
+		samples[i] = sample{class: class, d: time.Since(start)}
+	}
+	// drop the outliers caused by preemption, GC and so on
+	ds := make([]time.Duration, n)
+	for i, s := range samples {
+		ds[i] = s.d
+	}
+	slices.Sort(ds)
+	cutoff := ds[n*9/10]
+
+	var cnt [2]float64
+	var mean, m2 [2]float64
+	for _, s := range samples {
+		if s.d > cutoff {
+			continue
+		}
+		// Welford's online algorithm
+		c, x := s.class, float64(s.d)
+		cnt[c]++
+		delta := x - mean[c]
+		mean[c] += delta / cnt[c]
+		m2[c] += delta * (x - mean[c])
+	}
+	v0, v1 := m2[0]/(cnt[0]-1), m2[1]/(cnt[1]-1)
+	return (mean[0] - mean[1]) / math.Sqrt(v0/cnt[0]+v1/cnt[1])
+}
+
+// tThreshold is the threshold of dudect.
+const tThreshold = 4.5
+
+// The timing tests are statistical and may be flaky on a noisy machine such as a shared CI runner,
+// so they run only when SECURECOMPARE_TIMING_TEST=1.
+//
+//	SECURECOMPARE_TIMING_TEST=1 go test -run Timing -v ./src/crypto/securecompare/
+func skipTimingTest(t *testing.T) {
+	t.Helper()
+	if os.Getenv("SECURECOMPARE_TIMING_TEST") != "1" {
+		t.Skip("set SECURECOMPARE_TIMING_TEST=1 to run the timing test")
+	}
+}
+
+func TestTimingHarnessDetectsLeak(t *testing.T) {
+	skipTimingTest(t)
+	// bytes.Equal returns at the first difference, so it should be detected as leaky
+	secret := []byte(strings.Repeat("s", 4096))
+	inputs := [2][]byte{bytes.Clone(secret), []byte("x" + strings.Repeat("s", 4095))}
+	var sink bool
+	tt := timingT(20000, 10, func(class int) {
+		sink = bytes.Equal(secret, inputs[class])
+	})
+	_ = sink
+	t.Logf("t = %.2f", tt)
+	if math.Abs(tt) < tThreshold {
+		t.Errorf("|t| got %.2f, want >= %v for bytes.Equal", tt, tThreshold)
+	}
+}
+
+func TestTimingHarnessDetectsLengthLeak(t *testing.T) {
+	skipTimingTest(t)
+	// subtle.ConstantTimeCompare returns immediately when the lengths differ
+	secret := []byte(strings.Repeat("s", 4096))
+	inputs := [2][]byte{[]byte(strings.Repeat("x", 4096)), []byte(strings.Repeat("x", 4095))}
+	var sink int
+	tt := timingT(20000, 10, func(class int) {
+		sink = subtle.ConstantTimeCompare(secret, inputs[class])
+	})
+	_ = sink
+	t.Logf("t = %.2f", tt)
+	if math.Abs(tt) < tThreshold {
+		t.Errorf("|t| got %.2f, want >= %v for subtle.ConstantTimeCompare", tt, tThreshold)
+	}
+}
+
+func TestEqualTiming(t *testing.T) {
+	skipTimingTest(t)
+	secret := "0123456789abcdef0123456789abcdef"
+	tests := []struct {
+		name    string
+		secrets [2]string
+		inputs  [2]string
+	}{
+		{
+			name:    "match vs first byte differs",
+			secrets: [2]string{secret, secret},
+			inputs:  [2]string{secret, "X123456789abcdef0123456789abcdef"},
+		},
+		{
+			// the secret is the same and only whether the input length matches it differs
+			name:    "equal vs different length",
+			secrets: [2]string{secret, secret},
+			inputs:  [2]string{"X123456789abcdef0123456789abcdef", "X123456789abcdef0123456789abcde"},
+		},
+		{
+			name:    "first vs last byte differs",
+			secrets: [2]string{secret, secret},
+			inputs:  [2]string{"X123456789abcdef0123456789abcdef", "0123456789abcdef0123456789abcdeX"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var sink bool
+			tt := timingT(20000, 10, func(class int) {
+				sink = Equal(tc.secrets[class], tc.inputs[class])
+			})
+			_ = sink
+			t.Logf("t = %.2f", tt)
+			if math.Abs(tt) >= tThreshold {
+				t.Errorf("|t| got %.2f, want < %v", tt, tThreshold)
+			}
+		})
+	}
+}
+
+func TestSetIndexTiming(t *testing.T) {
+	skipTimingTest(t)
+	candidates := make([]string, 64)
+	for i := range candidates {
+		candidates[i] = strings.Repeat(string(rune('a'+i%26)), 32)
+	}
+	s := NewSet(candidates...)
+	tests := []struct {
+		name   string
+		inputs [2]string
+	}{
+		{name: "first vs last candidate", inputs: [2]string{candidates[0], candidates[63]}},
+		{name: "found vs not found", inputs: [2]string{candidates[0], strings.Repeat("-", 32)}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var sink bool
+			tt := timingT(20000, 2, func(class int) {
+				_, sink = s.Index(tc.inputs[class])
+			})
+			_ = sink
+			t.Logf("t = %.2f", tt)
+			if math.Abs(tt) >= tThreshold {
+				t.Errorf("|t| got %.2f, want < %v", tt, tThreshold)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/kei2100/playground-go/src/crypto/argon2"
+	"github.com/kei2100/playground-go/src/crypto/cipher"
+)
+
+// The private key file is as follows:
+//
+//	untrusted comment: signing encrypted secret key
+//	base64("Ed" || "A2" || cipher.EncryptWithPassphraseParams(key id || seed))
+//
+// "A2" stands for the Argon2 KDF. The KDF parameters and the salt are recorded by cipher.EncryptWithPassphraseParams.
+// Unlike minisign, the key id is encrypted together, since cipher.EncryptWithPassphraseParams takes no additional data.
+const (
+	privateKeyComment = "signing encrypted secret key"
+	kdfArgon2         = "A2"
+)
+
+// MarshalPrivateKey encrypts the k with the passphrase using Argon2id with the argon2.DefaultParams.
+func MarshalPrivateKey(k *PrivateKey, passphrase string) ([]byte, error) {
+	return MarshalPrivateKeyWithParams(k, passphrase, argon2.DefaultParams)
+}
+
+// MarshalPrivateKeyWithParams is like MarshalPrivateKey, but uses the params for the KDF.
+func MarshalPrivateKeyWithParams(k *PrivateKey, passphrase string, params argon2.Params) ([]byte, error) {
+	text := make([]byte, 0, KeyIDSize+ed25519.SeedSize)
+	text = append(text, k.ID[:]...)
+	text = append(text, k.Key.Seed()...)
+	defer clear(text)
+	encrypted, err := cipher.EncryptWithPassphraseParams(passphrase, text, params)
+	if err != nil {
+		return nil, fmt.Errorf("signing: encrypt private key: %w", err)
+	}
+	bin := append([]byte(algorithmLegacy+kdfArgon2), encrypted...)
+	return []byte(untrustedCommentPrefix + privateKeyComment + "\n" + base64.StdEncoding.EncodeToString(bin) + "\n"), nil
+}
+
+// ParsePrivateKey decrypts the private key file created by MarshalPrivateKey.
+// It returns an error wrapping cipher.ErrWrongPassphrase if the passphrase is wrong,
+// and cipher.ErrKDFLimitExceeded if the KDF parameters exceed the cipher.DefaultPassphraseLimits.
+func ParsePrivateKey(data []byte, passphrase string) (*PrivateKey, error) {
+	return ParsePrivateKeyWithLimits(data, passphrase, cipher.DefaultPassphraseLimits)
+}
+
+// ParsePrivateKeyWithLimits is like ParsePrivateKey, but rejects the KDF parameters exceeding the limits
+// (see cipher.DecryptWithPassphraseLimits).
+func ParsePrivateKeyWithLimits(data []byte, passphrase string, limits argon2.Params) (*PrivateKey, error) {
+	lines := splitLines(data)
+	if len(lines) > 0 && strings.HasPrefix(lines[0], untrustedCommentPrefix) {
+		lines = lines[1:]
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("signing: invalid private key: empty")
+	}
+	bin, err := base64.StdEncoding.DecodeString(lines[0])
+	if err != nil {
+		return nil, fmt.Errorf("signing: invalid private key: %w", err)
+	}
+	if len(bin) < 4 || string(bin[:2]) != algorithmLegacy || string(bin[2:4]) != kdfArgon2 {
+		return nil, fmt.Errorf("signing: invalid private key: unsupported format")
+	}
+	text, err := cipher.DecryptWithPassphraseLimits(passphrase, bin[4:], limits)
+	if err != nil {
+		return nil, fmt.Errorf("signing: decrypt private key: %w", err)
+	}
+	defer clear(text)
+	if len(text) != KeyIDSize+ed25519.SeedSize {
+		return nil, fmt.Errorf("signing: invalid private key: invalid length")
+	}
+	var k PrivateKey
+	copy(k.ID[:], text)
+	k.Key = ed25519.NewKeyFromSeed(text[KeyIDSize:])
+	return &k, nil
+}
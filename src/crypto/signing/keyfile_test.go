@@ -0,0 +1,43 @@
+package signing
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/kei2100/playground-go/src/crypto/argon2"
+	"github.com/kei2100/playground-go/src/crypto/cipher"
+)
+
+// testParams is cheap Argon2id parameters for tests.
+var testParams = argon2.Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestMarshalPrivateKey(t *testing.T) {
+	priv, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := MarshalPrivateKeyWithParams(priv, "passphrase", testParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, priv.Key.Seed()) {
+		t.Error("the seed is stored in plain")
+	}
+	got, err := ParsePrivateKey(data, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != priv.ID || !got.Key.Equal(priv.Key) {
+		t.Errorf("got %v, want %v", got.ID, priv.ID)
+	}
+	if _, err := ParsePrivateKey(data, "wrong"); !errors.Is(err, cipher.ErrWrongPassphrase) {
+		t.Errorf("err got %v, want cipher.ErrWrongPassphrase", err)
+	}
+	if _, err := ParsePrivateKey(readTestdata(t, "minisign.pub"), "passphrase"); err == nil {
+		t.Error("want an error for a public key")
+	}
+	if _, err := ParsePrivateKeyWithLimits(data, "passphrase", argon2.Params{Memory: testParams.Memory - 1, Iterations: 1, Parallelism: 1}); !errors.Is(err, cipher.ErrKDFLimitExceeded) {
+		t.Errorf("err got %v, want cipher.ErrKDFLimitExceeded", err)
+	}
+}
@@ -0,0 +1,232 @@
+package signing
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// The signature algorithms of minisign.
+// "Ed" signs the message itself (legacy), and "ED" signs the BLAKE2b-512 hash of the message.
+const (
+	algorithmLegacy    = "Ed"
+	algorithmPrehashed = "ED"
+)
+
+const (
+	untrustedCommentPrefix = "untrusted comment: "
+	trustedCommentPrefix   = "trusted comment: "
+	maxCommentLength       = 1024
+)
+
+// Signature is a signature file compatible with minisign.
+//
+//	untrusted comment: <UntrustedComment>
+//	base64(algorithm || key id || signature)
+//	trusted comment: <TrustedComment>
+//	base64(global signature)
+//
+// The global signature signs the signature and the trusted comment, so that the trusted comment cannot be
+// modified without the private key. The untrusted comment is not signed.
+type Signature struct {
+	// Prehashed reports whether the signature signs the BLAKE2b-512 hash of the message.
+	// SignFile always creates prehashed signatures.
+	Prehashed        bool
+	KeyID            KeyID
+	Signature        []byte
+	UntrustedComment string
+	TrustedComment   string
+	GlobalSignature  []byte
+}
+
+// SignFile reads the message from the r and returns the prehashed signature of it.
+// If the trustedComment is empty, "timestamp:<unix time>" is used like minisign.
+func (k *PrivateKey) SignFile(r io.Reader, untrustedComment, trustedComment string) (*Signature, error) {
+	if trustedComment == "" {
+		trustedComment = "timestamp:" + strconv.FormatInt(time.Now().Unix(), 10)
+	}
+	if err := validateComment(untrustedComment); err != nil {
+		return nil, err
+	}
+	if err := validateComment(trustedComment); err != nil {
+		return nil, err
+	}
+	digest, err := prehash(r)
+	if err != nil {
+		return nil, err
+	}
+	sig := ed25519.Sign(k.Key, digest)
+	return &Signature{
+		Prehashed:        true,
+		KeyID:            k.ID,
+		Signature:        sig,
+		UntrustedComment: untrustedComment,
+		TrustedComment:   trustedComment,
+		GlobalSignature:  ed25519.Sign(k.Key, globalMessage(sig, trustedComment)),
+	}, nil
+}
+
+// VerifyFile reads the message from the r and verifies the sig of it.
+// Both the prehashed and the legacy signatures are supported.
+// It returns ErrInvalidSignature if the key id does not match, or the signature or the trusted comment is invalid.
+func (k *PublicKey) VerifyFile(r io.Reader, sig *Signature) error {
+	if sig.KeyID != k.ID {
+		return fmt.Errorf("%w: key id %s does not match the public key %s", ErrInvalidSignature, sig.KeyID, k.ID)
+	}
+	var message []byte
+	var err error
+	if sig.Prehashed {
+		message, err = prehash(r)
+	} else {
+		message, err = io.ReadAll(r)
+	}
+	if err != nil {
+		return fmt.Errorf("signing: read message: %w", err)
+	}
+	if !ed25519.Verify(k.Key, message, sig.Signature) {
+		return ErrInvalidSignature
+	}
+	if !ed25519.Verify(k.Key, globalMessage(sig.Signature, sig.TrustedComment), sig.GlobalSignature) {
+		return fmt.Errorf("%w: trusted comment", ErrInvalidSignature)
+	}
+	return nil
+}
+
+// MarshalText encodes the s as a minisign signature file.
+func (s *Signature) MarshalText() ([]byte, error) {
+	if err := validateComment(s.UntrustedComment); err != nil {
+		return nil, err
+	}
+	if err := validateComment(s.TrustedComment); err != nil {
+		return nil, err
+	}
+	alg := algorithmLegacy
+	if s.Prehashed {
+		alg = algorithmPrehashed
+	}
+	bin := make([]byte, 0, 2+KeyIDSize+ed25519.SignatureSize)
+	bin = append(bin, alg...)
+	bin = append(bin, s.KeyID[:]...)
+	bin = append(bin, s.Signature...)
+
+	var b bytes.Buffer
+	b.WriteString(untrustedCommentPrefix + s.UntrustedComment + "\n")
+	b.WriteString(base64.StdEncoding.EncodeToString(bin) + "\n")
+	b.WriteString(trustedCommentPrefix + s.TrustedComment + "\n")
+	b.WriteString(base64.StdEncoding.EncodeToString(s.GlobalSignature) + "\n")
+	return b.Bytes(), nil
+}
+
+// ParseSignature parses the minisign signature file.
+func ParseSignature(data []byte) (*Signature, error) {
+	lines := splitLines(data)
+	if len(lines) < 4 {
+		return nil, fmt.Errorf("signing: invalid signature file: want 4 lines, got %d", len(lines))
+	}
+	untrusted, ok := strings.CutPrefix(lines[0], untrustedCommentPrefix)
+	if !ok {
+		return nil, fmt.Errorf("signing: invalid signature file: untrusted comment not found")
+	}
+	trusted, ok := strings.CutPrefix(lines[2], trustedCommentPrefix)
+	if !ok {
+		return nil, fmt.Errorf("signing: invalid signature file: trusted comment not found")
+	}
+	bin, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil {
+		return nil, fmt.Errorf("signing: invalid signature file: %w", err)
+	}
+	if len(bin) != 2+KeyIDSize+ed25519.SignatureSize {
+		return nil, fmt.Errorf("signing: invalid signature file: invalid signature length %d", len(bin))
+	}
+	s := Signature{UntrustedComment: untrusted, TrustedComment: trusted}
+	switch alg := string(bin[:2]); alg {
+	case algorithmPrehashed:
+		s.Prehashed = true
+	case algorithmLegacy:
+	default:
+		return nil, fmt.Errorf("signing: invalid signature file: unsupported algorithm %q", alg)
+	}
+	copy(s.KeyID[:], bin[2:])
+	s.Signature = bin[2+KeyIDSize:]
+	if s.GlobalSignature, err = base64.StdEncoding.DecodeString(lines[3]); err != nil {
+		return nil, fmt.Errorf("signing: invalid signature file: %w", err)
+	}
+	if len(s.GlobalSignature) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("signing: invalid signature file: invalid global signature length %d", len(s.GlobalSignature))
+	}
+	return &s, nil
+}
+
+// MarshalText encodes the k as a minisign public key file.
+func (k *PublicKey) MarshalText() ([]byte, error) {
+	return []byte(untrustedCommentPrefix + "minisign public key " + k.ID.String() + "\n" + k.String() + "\n"), nil
+}
+
+// String returns the base64 encoded public key as `minisign -P` accepts.
+func (k *PublicKey) String() string {
+	bin := make([]byte, 0, 2+KeyIDSize+ed25519.PublicKeySize)
+	bin = append(bin, algorithmLegacy...)
+	bin = append(bin, k.ID[:]...)
+	bin = append(bin, k.Key...)
+	return base64.StdEncoding.EncodeToString(bin)
+}
+
+// ParsePublicKey parses the minisign public key file, or the base64 encoded public key only.
+func ParsePublicKey(data []byte) (*PublicKey, error) {
+	lines := splitLines(data)
+	if len(lines) > 0 && strings.HasPrefix(lines[0], untrustedCommentPrefix) {
+		lines = lines[1:]
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("signing: invalid public key: empty")
+	}
+	bin, err := base64.StdEncoding.DecodeString(lines[0])
+	if err != nil {
+		return nil, fmt.Errorf("signing: invalid public key: %w", err)
+	}
+	if len(bin) != 2+KeyIDSize+ed25519.PublicKeySize || string(bin[:2]) != algorithmLegacy {
+		return nil, fmt.Errorf("signing: invalid public key")
+	}
+	var k PublicKey
+	copy(k.ID[:], bin[2:])
+	k.Key = ed25519.PublicKey(bin[2+KeyIDSize:])
+	return &k, nil
+}
+
+func prehash(r io.Reader) ([]byte, error) {
+	h, _ := blake2b.New512(nil)
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, fmt.Errorf("signing: read message: %w", err)
+	}
+	return h.Sum(nil), nil
+}
+
+func globalMessage(sig []byte, trustedComment string) []byte {
+	return append(append([]byte(nil), sig...), trustedComment...)
+}
+
+func validateComment(comment string) error {
+	if len(comment) > maxCommentLength {
+		return fmt.Errorf("signing: comment too long")
+	}
+	if strings.ContainsAny(comment, "\r\n") {
+		return fmt.Errorf("signing: comment must not contain newlines")
+	}
+	return nil
+}
+
+// splitLines splits the data into lines without the empty trailing lines.
+func splitLines(data []byte) []string {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
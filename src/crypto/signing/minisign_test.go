@@ -0,0 +1,152 @@
+package signing
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// The testdata is created with libsodium following the minisign format:
+// the key pair is crypto_sign_seed_keypair(0x00..0x1f), and artifact.txt.minisig signs the
+// crypto_generichash (BLAKE2b-512) of the artifact.txt.
+func TestVerifyFile_Minisign(t *testing.T) {
+	pub, err := ParsePublicKey(readTestdata(t, "minisign.pub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := pub.ID.String(), "E7620F1842B4E81F"; got != want {
+		t.Errorf("key id got %v, want %v", got, want)
+	}
+	message := readTestdata(t, "artifact.txt")
+	for _, name := range []string{"artifact.txt.minisig", "artifact.txt.legacy.minisig"} {
+		t.Run(name, func(t *testing.T) {
+			sig, err := ParseSignature(readTestdata(t, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := sig.TrustedComment, "timestamp:1760745600\tfile:artifact.txt\thashed"; got != want {
+				t.Errorf("trusted comment got %q, want %q", got, want)
+			}
+			if got, want := sig.UntrustedComment, "signature from minisign secret key"; got != want {
+				t.Errorf("untrusted comment got %q, want %q", got, want)
+			}
+			if err := pub.VerifyFile(bytes.NewReader(message), sig); err != nil {
+				t.Errorf("VerifyFile: %v", err)
+			}
+			if err := pub.VerifyFile(strings.NewReader("tampered"), sig); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("err got %v, want ErrInvalidSignature", err)
+			}
+			// round trip
+			text, err := sig.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := readTestdata(t, name); !bytes.Equal(text, want) {
+				t.Errorf("MarshalText got\n%s\nwant\n%s", text, want)
+			}
+		})
+	}
+
+	text, err := pub.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := readTestdata(t, "minisign.pub"); !bytes.Equal(text, want) {
+		t.Errorf("public key MarshalText got\n%s\nwant\n%s", text, want)
+	}
+	if _, err := ParsePublicKey([]byte(pub.String())); err != nil {
+		t.Errorf("ParsePublicKey of the base64 only: %v", err)
+	}
+}
+
+func TestSignFile(t *testing.T) {
+	priv, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := priv.Public()
+	message := "artifact"
+	sig, err := priv.SignFile(strings.NewReader(message), "signature from signing", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sig.Prehashed || sig.KeyID != priv.ID {
+		t.Errorf("got prehashed=%v key id=%v", sig.Prehashed, sig.KeyID)
+	}
+	if !strings.HasPrefix(sig.TrustedComment, "timestamp:") {
+		t.Errorf("default trusted comment got %q", sig.TrustedComment)
+	}
+	text, err := sig.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseSignature(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pub.VerifyFile(strings.NewReader(message), parsed); err != nil {
+		t.Errorf("VerifyFile: %v", err)
+	}
+
+	t.Run("tampered trusted comment", func(t *testing.T) {
+		tampered := *parsed
+		tampered.TrustedComment = "timestamp:0"
+		if err := pub.VerifyFile(strings.NewReader(message), &tampered); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("err got %v, want ErrInvalidSignature", err)
+		}
+	})
+	t.Run("untrusted comment is not signed", func(t *testing.T) {
+		modified := *parsed
+		modified.UntrustedComment = "anything"
+		if err := pub.VerifyFile(strings.NewReader(message), &modified); err != nil {
+			t.Errorf("VerifyFile: %v", err)
+		}
+	})
+	t.Run("key id mismatch", func(t *testing.T) {
+		other, err := GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := other.Public().VerifyFile(strings.NewReader(message), parsed); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("err got %v, want ErrInvalidSignature", err)
+		}
+	})
+	t.Run("comment with newline", func(t *testing.T) {
+		if _, err := priv.SignFile(strings.NewReader(message), "a\nb", ""); err == nil {
+			t.Error("want an error")
+		}
+	})
+}
+
+func TestParseSignature_Invalid(t *testing.T) {
+	valid := string(readTestdata(t, "artifact.txt.minisig"))
+	lines := strings.Split(valid, "\n")
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "empty", data: ""},
+		{name: "no untrusted comment", data: strings.Join(append([]string{"comment"}, lines[1:]...), "\n")},
+		{name: "no trusted comment", data: strings.Join([]string{lines[0], lines[1], "comment", lines[3]}, "\n")},
+		{name: "invalid base64", data: strings.Join([]string{lines[0], "!!", lines[2], lines[3]}, "\n")},
+		{name: "unknown algorithm", data: strings.Replace(valid, "RUQf", "RXQf", 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseSignature([]byte(tt.data)); err == nil {
+				t.Error("want an error")
+			}
+		})
+	}
+}
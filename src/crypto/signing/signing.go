@@ -0,0 +1,74 @@
+// Package signing signs and verifies messages with Ed25519.
+//
+// Sign and Verify handle raw detached signatures.
+// SignFile and VerifyFile handle signature files compatible with minisign (https://jedisct1.github.io/minisign/),
+// and the public keys are also marshaled in the minisign format, so that the signatures can be verified by
+// `minisign -V`. The private keys are stored encrypted with a passphrase using Argon2id
+// (see MarshalPrivateKey), which is not compatible with minisign.
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidSignature is returned when the signature does not verify.
+var ErrInvalidSignature = errors.New("signing: invalid signature")
+
+// KeyIDSize is the size of the KeyID.
+const KeyIDSize = 8
+
+// KeyID is the random identifier of the key pair, which is recorded in the signatures and the public key.
+type KeyID [KeyIDSize]byte
+
+// String returns the key id as minisign displays, e.g. E7620F1842B4E81F
+func (id KeyID) String() string {
+	return fmt.Sprintf("%016X", binary.LittleEndian.Uint64(id[:]))
+}
+
+// PublicKey is an Ed25519 public key with the key id.
+type PublicKey struct {
+	ID  KeyID
+	Key ed25519.PublicKey
+}
+
+// PrivateKey is an Ed25519 private key with the key id.
+type PrivateKey struct {
+	ID  KeyID
+	Key ed25519.PrivateKey
+}
+
+// GenerateKey generates a new key pair with a random key id.
+func GenerateKey() (*PrivateKey, error) {
+	var id KeyID
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, fmt.Errorf("signing: generate key id: %w", err)
+	}
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("signing: generate key: %w", err)
+	}
+	return &PrivateKey{ID: id, Key: priv}, nil
+}
+
+// Public returns the public key of the k.
+func (k *PrivateKey) Public() *PublicKey {
+	return &PublicKey{ID: k.ID, Key: k.Key.Public().(ed25519.PublicKey)}
+}
+
+// Sign returns the detached Ed25519 signature of the message.
+func (k *PrivateKey) Sign(message []byte) []byte {
+	return ed25519.Sign(k.Key, message)
+}
+
+// Verify verifies the detached signature of the message created by Sign.
+// It returns ErrInvalidSignature if the signature is invalid.
+func (k *PublicKey) Verify(message, sig []byte) error {
+	if !ed25519.Verify(k.Key, message, sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
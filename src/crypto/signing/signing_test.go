@@ -0,0 +1,39 @@
+package signing
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSignVerify(t *testing.T) {
+	priv, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := priv.Public()
+	if pub.ID != priv.ID {
+		t.Errorf("public key id got %v, want %v", pub.ID, priv.ID)
+	}
+	message := []byte("hello")
+	sig := priv.Sign(message)
+	if err := pub.Verify(message, sig); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+	if err := pub.Verify([]byte("hellO"), sig); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("err got %v, want ErrInvalidSignature", err)
+	}
+	other, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Public().Verify(message, sig); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("err got %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestKeyID_String(t *testing.T) {
+	id := KeyID{0x1f, 0xe8, 0xb4, 0x42, 0x18, 0x0f, 0x62, 0xe7}
+	if got, want := id.String(), "E7620F1842B4E81F"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
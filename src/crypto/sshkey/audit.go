@@ -0,0 +1,92 @@
+package sshkey
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// MinRSABits is the minimum RSA key size not reported as weak.
+const MinRSABits = 2048
+
+// Weakness returns the reason why the key is weak, or empty if it is not weak.
+// RSA keys under MinRSABits bits and DSA keys are weak.
+func Weakness(key ssh.PublicKey) string {
+	typ, bits := keyTypeAndBits(key)
+	switch typ {
+	case "RSA", "RSA-CERT":
+		if bits < MinRSABits {
+			return fmt.Sprintf("RSA key of %d bits is less than %d bits", bits, MinRSABits)
+		}
+	case "DSA", "DSA-CERT":
+		return "DSA keys are deprecated"
+	}
+	return ""
+}
+
+// FindingKind is the kind of the Finding.
+type FindingKind string
+
+const (
+	// FindingWeak reports a weak key.
+	FindingWeak FindingKind = "weak"
+	// FindingDuplicate reports a key that appears on an earlier line.
+	FindingDuplicate FindingKind = "duplicate"
+)
+
+// Finding is a problem found by Audit.
+type Finding struct {
+	Kind FindingKind
+	// Line is the line number of the key.
+	Line int
+	// Fingerprint is the SHA256 fingerprint of the key.
+	Fingerprint string
+	// Detail describes the problem.
+	Detail string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("line %d: %s: %s: %s", f.Line, f.Kind, f.Fingerprint, f.Detail)
+}
+
+// AuditAuthorizedKeys reports the weak and duplicate keys of the authorized_keys entries.
+func AuditAuthorizedKeys(keys []AuthorizedKey) []Finding {
+	var a auditor
+	for _, k := range keys {
+		a.check(k.Line, k.Key)
+	}
+	return a.findings
+}
+
+// AuditKnownHosts reports the weak and duplicate keys of the known_hosts entries.
+// The @revoked entries are skipped, since listing a weak key as revoked is not a problem.
+func AuditKnownHosts(hosts []KnownHost) []Finding {
+	var a auditor
+	for _, h := range hosts {
+		if h.Marker == "@revoked" {
+			continue
+		}
+		a.check(h.Line, h.Key)
+	}
+	return a.findings
+}
+
+type auditor struct {
+	seen     map[string]int // fingerprint -> first line
+	findings []Finding
+}
+
+func (a *auditor) check(line int, key ssh.PublicKey) {
+	if a.seen == nil {
+		a.seen = make(map[string]int)
+	}
+	fp := ssh.FingerprintSHA256(key)
+	if reason := Weakness(key); reason != "" {
+		a.findings = append(a.findings, Finding{Kind: FindingWeak, Line: line, Fingerprint: fp, Detail: reason})
+	}
+	if first, ok := a.seen[fp]; ok {
+		a.findings = append(a.findings, Finding{Kind: FindingDuplicate, Line: line, Fingerprint: fp, Detail: fmt.Sprintf("same key as line %d", first)})
+		return
+	}
+	a.seen[fp] = line
+}
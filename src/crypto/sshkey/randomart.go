@@ -0,0 +1,89 @@
+package sshkey
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Randomart returns the visual host key of the SHA256 fingerprint, same as `ssh-keygen -lv`.
+// It is the "drunken bishop" algorithm of OpenSSH sshkey.c fingerprint_randomart.
+//
+//	+--[ED25519 256]--+
+//	|            ooooo|
+//	|           ..Boo.|
+//	|            + =E |
+//	|        . .=oo   |
+//	|        S=+*o= . |
+//	|         o*.*++.o|
+//	|         .oo+o+.=|
+//	|         oo..+.=o|
+//	|         .o  o= o|
+//	+----[SHA256]-----+
+func Randomart(key ssh.PublicKey) string {
+	const (
+		fieldBase    = 8
+		fieldY       = fieldBase + 1
+		fieldX       = fieldBase*2 + 1
+		augmentation = " .o+=*BOX@%&#/^SE"
+		end          = len(augmentation) - 1
+	)
+	digest := sha256.Sum256(key.Marshal())
+
+	var field [fieldX][fieldY]int
+	x, y := fieldX/2, fieldY/2
+	for _, input := range digest {
+		// each byte conveys four 2-bit move commands
+		for range 4 {
+			if input&0x1 != 0 {
+				x++
+			} else {
+				x--
+			}
+			if input&0x2 != 0 {
+				y++
+			} else {
+				y--
+			}
+			x = min(max(x, 0), fieldX-1)
+			y = min(max(y, 0), fieldY-1)
+			if field[x][y] < end-2 {
+				field[x][y]++
+			}
+			input >>= 2
+		}
+	}
+	// mark the start and end points
+	field[fieldX/2][fieldY/2] = end - 1
+	field[x][y] = end
+
+	typ, bits := keyTypeAndBits(key)
+	title := fmt.Sprintf("[%s %d]", typ, bits)
+	if len(title) > fieldX {
+		title = fmt.Sprintf("[%s]", typ)
+	}
+
+	var b strings.Builder
+	writeBorder(&b, title, fieldX)
+	for y := range fieldY {
+		b.WriteByte('|')
+		for x := range fieldX {
+			b.WriteByte(augmentation[min(field[x][y], end)])
+		}
+		b.WriteString("|\n")
+	}
+	writeBorder(&b, "[SHA256]", fieldX)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func writeBorder(b *strings.Builder, label string, width int) {
+	label = label[:min(len(label), width)]
+	left := (width - len(label)) / 2
+	b.WriteByte('+')
+	b.WriteString(strings.Repeat("-", left))
+	b.WriteString(label)
+	b.WriteString(strings.Repeat("-", width-left-len(label)))
+	b.WriteString("+\n")
+}
@@ -0,0 +1,154 @@
+// Package sshkey parses authorized_keys and known_hosts files,
+// and inspects the SSH public keys in them: fingerprints, weak keys, duplicates and randomart.
+package sshkey
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/dsa" //nolint:staticcheck // to inspect legacy DSA keys
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// AuthorizedKey is an entry of an authorized_keys file.
+type AuthorizedKey struct {
+	// Line is the 1-based line number in the file.
+	Line    int
+	Options []string
+	Key     ssh.PublicKey
+	Comment string
+}
+
+// KnownHost is an entry of a known_hosts file.
+type KnownHost struct {
+	// Line is the 1-based line number in the file.
+	Line int
+	// Marker is "@cert-authority", "@revoked" or empty.
+	Marker string
+	// Hosts is the host patterns. A hashed host name is kept as it is (|1|salt|hash).
+	Hosts   []string
+	Key     ssh.PublicKey
+	Comment string
+}
+
+// ParseAuthorizedKeys parses the authorized_keys file.
+// Empty lines and comment lines are skipped.
+func ParseAuthorizedKeys(r io.Reader) ([]AuthorizedKey, error) {
+	var keys []AuthorizedKey
+	err := scanLines(r, func(line int, text []byte) error {
+		key, comment, options, _, err := ssh.ParseAuthorizedKey(text)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, AuthorizedKey{Line: line, Options: options, Key: key, Comment: comment})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// ParseKnownHosts parses the known_hosts file.
+// Empty lines and comment lines are skipped.
+func ParseKnownHosts(r io.Reader) ([]KnownHost, error) {
+	var hosts []KnownHost
+	err := scanLines(r, func(line int, text []byte) error {
+		marker, hs, key, comment, _, err := ssh.ParseKnownHosts(text)
+		if err != nil {
+			return err
+		}
+		if marker != "" {
+			marker = "@" + marker
+		}
+		hosts = append(hosts, KnownHost{Line: line, Marker: marker, Hosts: hs, Key: key, Comment: comment})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hosts, nil
+}
+
+func scanLines(r io.Reader, fn func(line int, text []byte) error) error {
+	s := bufio.NewScanner(r)
+	// RSA 16384 bits keys with options may exceed the default 64 KiB
+	s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; s.Scan(); line++ {
+		text := bytes.TrimSpace(s.Bytes())
+		if len(text) == 0 || text[0] == '#' {
+			continue
+		}
+		if err := fn(line, text); err != nil {
+			return fmt.Errorf("sshkey: line %d: %w", line, err)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return fmt.Errorf("sshkey: read: %w", err)
+	}
+	return nil
+}
+
+// Info is the summary of a public key like `ssh-keygen -l`.
+type Info struct {
+	// Type is the key type as OpenSSH displays, e.g. RSA, ED25519 or ECDSA-CERT.
+	Type string
+	// Bits is the key size in bits.
+	Bits int
+	// SHA256 is the fingerprint, e.g. SHA256:Snf3igD5pr/RYc0kV8eYYJAhTa08b69seeR76Id5684
+	SHA256 string
+	// MD5 is the legacy fingerprint without the "MD5:" prefix, e.g. c7:9a:03:86:86:8d:c7:06:99:32:88:8c:ac:99:ee:0a
+	MD5 string
+}
+
+// Inspect returns the Info of the key.
+func Inspect(key ssh.PublicKey) Info {
+	typ, bits := keyTypeAndBits(key)
+	return Info{
+		Type:   typ,
+		Bits:   bits,
+		SHA256: ssh.FingerprintSHA256(key),
+		MD5:    ssh.FingerprintLegacyMD5(key),
+	}
+}
+
+func keyTypeAndBits(key ssh.PublicKey) (string, int) {
+	suffix := ""
+	if cert, ok := key.(*ssh.Certificate); ok {
+		key, suffix = cert.Key, "-CERT"
+	}
+	var typ string
+	var bits int
+	switch key.Type() {
+	case ssh.KeyAlgoRSA:
+		typ = "RSA"
+	case ssh.KeyAlgoDSA:
+		typ = "DSA"
+	case ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521:
+		typ = "ECDSA"
+	case ssh.KeyAlgoED25519:
+		typ, bits = "ED25519", 256
+	case ssh.KeyAlgoSKECDSA256:
+		typ, bits = "ECDSA-SK", 256
+	case ssh.KeyAlgoSKED25519:
+		typ, bits = "ED25519-SK", 256
+	default:
+		typ = strings.ToUpper(key.Type())
+	}
+	if ck, ok := key.(ssh.CryptoPublicKey); ok {
+		switch pub := ck.CryptoPublicKey().(type) {
+		case *rsa.PublicKey:
+			bits = pub.N.BitLen()
+		case *dsa.PublicKey:
+			bits = pub.P.BitLen()
+		case *ecdsa.PublicKey:
+			bits = pub.Curve.Params().BitSize
+		}
+	}
+	return typ + suffix, bits
+}
@@ -0,0 +1,178 @@
+package sshkey
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func openTestdata(t *testing.T, name string) *os.File {
+	t.Helper()
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+// expected values are generated by `ssh-keygen -lv -f` (OpenSSH 9.2)
+const (
+	fpED25519  = "SHA256:T1n0ReIsrYvOln2BSA5v5YOd8dYjf9O1uCeDBVZcC/Y"
+	fpRSA2048  = "SHA256:U9wOX/0p6qEBFLDGyhwVg0Vm8RNfJO14E21gPaQkLP4"
+	fpRSA1024  = "SHA256:9uh9NBacYMgAHokelu4cv5o2o0dht7hU6Z9pTi34Ny8"
+	fpDSA      = "SHA256:CZv7TqsnTNfsfDMVuNXxDQQjcQGuAkzUuvmBx/Nf1h8"
+	fpECDSA384 = "SHA256:IDfYq9iG95rV8RGg1HjsZxGHLlYS2UcOhsoZKgx6Pbg"
+)
+
+func TestParseAuthorizedKeys(t *testing.T) {
+	keys, err := ParseAuthorizedKeys(openTestdata(t, "authorized_keys"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		line    int
+		comment string
+		options []string
+		info    Info
+	}{
+		{line: 2, comment: "alice@example.com", info: Info{Type: "ED25519", Bits: 256, SHA256: fpED25519, MD5: "2c:13:9d:0c:c9:78:99:47:e5:fd:65:55:b1:bd:ce:07"}},
+		{line: 4, comment: "bob", options: []string{"no-port-forwarding", `command="/usr/bin/backup"`, `from="10.0.0.0/8"`}, info: Info{Type: "RSA", Bits: 2048, SHA256: fpRSA2048}},
+		{line: 5, comment: "weak", info: Info{Type: "RSA", Bits: 1024, SHA256: fpRSA1024}},
+		{line: 6, comment: "legacy", info: Info{Type: "DSA", Bits: 1024, SHA256: fpDSA}},
+		{line: 7, comment: "alice-again", options: []string{`environment="LANG=C"`}, info: Info{Type: "ED25519", Bits: 256, SHA256: fpED25519}},
+		{line: 8, comment: "", info: Info{Type: "ECDSA", Bits: 384, SHA256: fpECDSA384}},
+	}
+	if len(keys) != len(tests) {
+		t.Fatalf("len(keys) got %v, want %v", len(keys), len(tests))
+	}
+	for i, tt := range tests {
+		k := keys[i]
+		if k.Line != tt.line {
+			t.Errorf("[%d] line got %v, want %v", i, k.Line, tt.line)
+		}
+		if k.Comment != tt.comment {
+			t.Errorf("[%d] comment got %q, want %q", i, k.Comment, tt.comment)
+		}
+		if !reflect.DeepEqual(k.Options, tt.options) {
+			t.Errorf("[%d] options got %q, want %q", i, k.Options, tt.options)
+		}
+		info := Inspect(k.Key)
+		if tt.info.MD5 == "" {
+			info.MD5 = ""
+		}
+		if info != tt.info {
+			t.Errorf("[%d] info got %+v, want %+v", i, info, tt.info)
+		}
+	}
+}
+
+func TestParseAuthorizedKeys_Error(t *testing.T) {
+	_, err := ParseAuthorizedKeys(strings.NewReader("# comment\n\nssh-ed25519 invalid\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("err got %v, want an error of line 3", err)
+	}
+}
+
+func TestParseKnownHosts(t *testing.T) {
+	hosts, err := ParseKnownHosts(openTestdata(t, "known_hosts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		line    int
+		marker  string
+		hosts   []string
+		comment string
+		sha256  string
+	}{
+		{line: 2, hosts: []string{"github.com", "140.82.112.3"}, sha256: fpED25519},
+		{line: 3, hosts: []string{"[bastion.example.com]:2222"}, comment: "bastion", sha256: fpRSA2048},
+		{line: 4, hosts: []string{"|1|JfKTdBh7rNbXkVAQCRp4OQoPfmI=|USECr3SWf1JUPsms5AqfD5QfxkM="}, sha256: fpECDSA384},
+		{line: 5, marker: "@cert-authority", hosts: []string{"*.example.com"}, comment: "ca", sha256: fpED25519},
+		{line: 6, marker: "@revoked", hosts: []string{"*"}, sha256: fpRSA1024},
+	}
+	if len(hosts) != len(tests) {
+		t.Fatalf("len(hosts) got %v, want %v", len(hosts), len(tests))
+	}
+	for i, tt := range tests {
+		h := hosts[i]
+		if h.Line != tt.line || h.Marker != tt.marker || h.Comment != tt.comment {
+			t.Errorf("[%d] got line=%v marker=%q comment=%q, want line=%v marker=%q comment=%q",
+				i, h.Line, h.Marker, h.Comment, tt.line, tt.marker, tt.comment)
+		}
+		if !reflect.DeepEqual(h.Hosts, tt.hosts) {
+			t.Errorf("[%d] hosts got %q, want %q", i, h.Hosts, tt.hosts)
+		}
+		if got := Inspect(h.Key).SHA256; got != tt.sha256 {
+			t.Errorf("[%d] fingerprint got %v, want %v", i, got, tt.sha256)
+		}
+	}
+}
+
+func TestAuditAuthorizedKeys(t *testing.T) {
+	keys, err := ParseAuthorizedKeys(openTestdata(t, "authorized_keys"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Finding{
+		{Kind: FindingWeak, Line: 5, Fingerprint: fpRSA1024, Detail: "RSA key of 1024 bits is less than 2048 bits"},
+		{Kind: FindingWeak, Line: 6, Fingerprint: fpDSA, Detail: "DSA keys are deprecated"},
+		{Kind: FindingDuplicate, Line: 7, Fingerprint: fpED25519, Detail: "same key as line 2"},
+	}
+	if got := AuditAuthorizedKeys(keys); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestAuditKnownHosts(t *testing.T) {
+	hosts, err := ParseKnownHosts(openTestdata(t, "known_hosts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the weak key of @revoked is not reported
+	want := []Finding{
+		{Kind: FindingDuplicate, Line: 5, Fingerprint: fpED25519, Detail: "same key as line 2"},
+	}
+	if got := AuditKnownHosts(hosts); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRandomart(t *testing.T) {
+	keys, err := ParseAuthorizedKeys(openTestdata(t, "authorized_keys"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"+--[ED25519 256]--+",
+		"|            ooooo|",
+		"|           ..Boo.|",
+		"|            + =E |",
+		"|        . .=oo   |",
+		"|        S=+*o= . |",
+		"|         o*.*++.o|",
+		"|         .oo+o+.=|",
+		"|         oo..+.=o|",
+		"|         .o  o= o|",
+		"+----[SHA256]-----+",
+	}, "\n")
+	if got := Randomart(keys[0].Key); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	titles := map[int]string{1: "+---[RSA 2048]----+", 5: "+---[ECDSA 384]---+"}
+	for i, title := range titles {
+		lines := strings.Split(Randomart(keys[i].Key), "\n")
+		if len(lines) != 11 {
+			t.Fatalf("[%d] len(lines) got %v, want 11", i, len(lines))
+		}
+		if lines[0] != title {
+			t.Errorf("[%d] title got %v, want %v", i, lines[0], title)
+		}
+		if lines[10] != "+----[SHA256]-----+" {
+			t.Errorf("[%d] footer got %v", i, lines[10])
+		}
+	}
+}
@@ -4,58 +4,210 @@ import (
 	"context"
 	"log/slog"
 	"slices"
+	"sync"
 )
 
 type contextKeyType struct{}
 
 var contextKey = contextKeyType{}
 
+type levelKeyType struct{}
+
+var levelKey = levelKeyType{}
+
 type node struct {
-	attr   slog.Attr
+	attr slog.Attr
+	// mask が true の node は WithoutAttr によるもので、祖先の attr.Key の属性を隠す
+	mask bool
+	// lazy が nil でなければ attr.Value の代わりに lazy() の値を出力する (WithLazyAttr)
+	lazy func() slog.Value
+	// group が空でなければ attr はルートの group 配下に出力する (WithGroupAttr)
+	group  string
 	parent *node
 }
 
 // WithAttr は attr を持つ新しい context を返します。
 // 同じ context に対し複数回呼ぶと属性が積み重なり、Handler 経由のログ出力時に
-// すべてトップレベル属性として書き出されます。親 context は変更しません。
+// すべてトップレベル属性として書き出されます。同じキーを複数回積んだ場合は
+// 最後に積んだもの (子 context 側) だけが出力されます。親 context は変更しません。
 func WithAttr(parent context.Context, attr slog.Attr) context.Context {
-	n := &node{attr: attr}
+	return push(parent, &node{attr: attr})
+}
+
+// WithLazyAttr は値を fn で遅延評価する key の属性を持つ新しい context を返します。
+// fn は Handler がこの属性を含むレコードを初めて出力するときに 1 度だけ呼ばれ、その値は
+// 返した context とその子孫で共有されます。レベルで除外されたログや、同じキーの WithAttr で
+// 上書きされた場合は呼ばれないため、計算コストの高い属性に使います。
+// fn は複数の goroutine から同時に出力されうる場合でも 1 度だけ呼ばれます。親 context は変更しません。
+func WithLazyAttr(parent context.Context, key string, fn func() slog.Value) context.Context {
+	return push(parent, &node{attr: slog.Attr{Key: key}, lazy: sync.OnceValue(fn)})
+}
+
+// WithGroupAttr は attrs を name の group 配下に持つ新しい context を返します。
+// Handler はこれを {"name":{"k":"v",...}} のようにルートの group として出力し、
+// WithAttr と同様に logger 側の WithGroup の影響を受けません。
+// 同じ name に対し複数回呼んだ場合は 1 つの group にまとめられ、group 内で同じキーを
+// 複数回積んだ場合は最後に積んだものだけが出力されます。
+// group 名はトップレベル属性のキーと同じ扱いで、同じ名前の WithAttr とは後から積んだ方だけが出力されます。
+// 親 context は変更しません。
+func WithGroupAttr(parent context.Context, name string, attrs ...slog.Attr) context.Context {
+	if name == "" {
+		for _, attr := range attrs {
+			parent = WithAttr(parent, attr)
+		}
+		return parent
+	}
+	for _, attr := range attrs {
+		parent = push(parent, &node{attr: attr, group: name})
+	}
+	return parent
+}
+
+// WithoutAttr は key の属性を隠した新しい context を返します。
+// 親 context までに積まれた key の属性は、返した context とその子孫では出力されません。
+// key が WithGroupAttr の group 名であれば group ごと隠します。
+// 返した context に改めて WithAttr した key の属性は出力されます。親 context は変更しません。
+func WithoutAttr(parent context.Context, key string) context.Context {
+	return push(parent, &node{attr: slog.Attr{Key: key}, mask: true})
+}
+
+func push(parent context.Context, n *node) context.Context {
 	if pn, ok := parent.Value(contextKey).(*node); ok {
 		n.parent = pn
 	}
 	return context.WithValue(parent, contextKey, n)
 }
 
-func attrsFromContext(ctx context.Context) []slog.Attr {
-	n, ok := ctx.Value(contextKey).(*node)
-	if !ok {
+// attrsFromContext は ctx に積まれた属性をルート→子の順に、続けて extractors が ctx から取り出した属性を
+// 登録順に返します。
+// keepAll が false ならキーごとに 1 つだけ残します。WithGroupAttr の group 名もキーとして扱います。
+// ctx の属性は最後に積まれたものをその位置に残し、extractors が取り出した属性は ctx の属性や
+// 先に登録された extractor と同じキーなら除きます (明示的に WithAttr した値を優先する)。
+// WithoutAttr で隠されたキーは keepAll に関わらず、extractors が取り出したものも含めて除きます。
+// WithGroupAttr の属性は group ごとに 1 つの slog.Group にまとめ、それらの後ろに最初に積まれた group の順で置きます。
+//
+// ホットパスのため、リストを葉から辿りながら既出キーを返り値の attrs 自身で線形探索し、
+// アロケーションを返り値の 1 回 (と WithoutAttr や WithGroupAttr を使った場合の分) に抑えています。
+// ctx 属性は通常数個なので map より速いです。
+func attrsFromContext(ctx context.Context, keepAll bool, extractors []AttrExtractor) []slog.Attr {
+	n, _ := ctx.Value(contextKey).(*node)
+	// extractors の結果は返り値の容量を決めるために先に取り出す。extractor は通常 1, 2 個なのでスタックに置く
+	var buf [4][]slog.Attr
+	extracted := buf[:0]
+	size := 0
+	for _, extract := range extractors {
+		if ea := extract(ctx); len(ea) > 0 {
+			extracted = append(extracted, ea)
+			size += len(ea)
+		}
+	}
+	if n == nil && size == 0 {
 		return nil
 	}
 
-	var attrs []slog.Attr
-	for {
-		attrs = append(attrs, n.attr)
-		if n.parent == nil {
-			break
+	for p := n; p != nil; p = p.parent {
+		size++
+	}
+	attrs := make([]slog.Attr, 0, size)
+	var masked []string
+	var grouped []groupedAttr
+	for ; n != nil; n = n.parent {
+		key := n.attr.Key
+		if n.group != "" {
+			if slices.Contains(masked, n.group) {
+				continue
+			}
+			if !keepAll && (slices.ContainsFunc(grouped, func(g groupedAttr) bool { return g.group == n.group && g.attr.Key == key }) ||
+				slices.ContainsFunc(attrs, func(a slog.Attr) bool { return a.Key == n.group })) {
+				continue
+			}
+			grouped = append(grouped, groupedAttr{group: n.group, attr: n.attr})
+			continue
 		}
-		n = n.parent
+		if slices.Contains(masked, key) {
+			continue
+		}
+		if n.mask {
+			masked = append(masked, key)
+			continue
+		}
+		if !keepAll && (slices.ContainsFunc(attrs, func(a slog.Attr) bool { return a.Key == key }) ||
+			slices.ContainsFunc(grouped, func(g groupedAttr) bool { return g.group == key })) {
+			continue
+		}
+		attr := n.attr
+		if n.lazy != nil {
+			attr.Value = n.lazy()
+		}
+		attrs = append(attrs, attr)
 	}
 	slices.Reverse(attrs)
+	for _, ea := range extracted {
+		for _, attr := range ea {
+			if slices.Contains(masked, attr.Key) {
+				continue
+			}
+			if !keepAll && (slices.ContainsFunc(attrs, func(a slog.Attr) bool { return a.Key == attr.Key }) ||
+				slices.ContainsFunc(grouped, func(g groupedAttr) bool { return g.group == attr.Key })) {
+				continue
+			}
+			attrs = append(attrs, attr)
+		}
+	}
+	if len(grouped) == 0 {
+		return attrs
+	}
+
+	// group ごとにまとめる
+	slices.Reverse(grouped)
+	for i, g := range grouped {
+		if slices.ContainsFunc(grouped[:i], func(prev groupedAttr) bool { return prev.group == g.group }) {
+			continue
+		}
+		var members []slog.Attr
+		for _, m := range grouped[i:] {
+			if m.group == g.group {
+				members = append(members, m.attr)
+			}
+		}
+		attrs = append(attrs, slog.Attr{Key: g.group, Value: slog.GroupValue(members...)})
+	}
 	return attrs
 }
 
+type groupedAttr struct {
+	group string
+	attr  slog.Attr
+}
+
+// WithLevel は level 以上のログを、ラップ先 Handler のレベル設定に関わらず出力する新しい context を返します。
+// 特定のリクエストやテナントだけ DEBUG ログを出したい場合に使います。
+// ラップ先のレベルの方が低い場合は、ラップ先のレベルが優先されます (低い方が効く)。
+// 親 context は変更しません。
+func WithLevel(parent context.Context, level slog.Level) context.Context {
+	return context.WithValue(parent, levelKey, level)
+}
+
+func levelFromContext(ctx context.Context) (slog.Level, bool) {
+	level, ok := ctx.Value(levelKey).(slog.Level)
+	return level, ok
+}
+
 var _ slog.Handler = &Handler{}
 
 // Handler は WithAttr で context に積まれた属性を、ラップした slog.Handler の
-// トップレベル属性として出力する slog.Handler 実装です。WithGroup を任意の深さで
-// 重ねても、ctx 由来の属性は group の外側 (ルート) に留まります。
+// トップレベル属性として出力する slog.Handler 実装です。WithGroupAttr の属性はルートの group として、
+// WithExtractor で登録した AttrExtractor が ctx から取り出した属性はトップレベル属性として出力します。
+// WithGroup を任意の深さで重ねても、ctx 由来の属性は group の外側 (ルート) に留まります。
 //
 // 実装ノート: WithGroup と group 配下の WithAttrs を遅延適用にし、Handle 時に
 // 「ctx 属性をラップ先に焼く → 保留した group / 内側 attrs を順に再生する」
 // という順で組み直すことで上記を実現しています。
 type Handler struct {
-	wrap   slog.Handler
-	groups []groupState // WithGroup と「group 内 WithAttrs」を遅延保持
+	wrap       slog.Handler
+	groups     []groupState // WithGroup と「group 内 WithAttrs」を遅延保持
+	keepAll    bool
+	extractors []AttrExtractor
 }
 
 type groupState struct {
@@ -63,23 +215,56 @@ type groupState struct {
 	attrs []slog.Attr
 }
 
+// Option は NewHandler のオプションです。
+type Option func(*Handler)
+
+// WithKeepAllAttrs は ctx に同じキーの属性が複数積まれていても、重複を除かずに
+// すべて出力するオプションです。WithoutAttr で隠したキーは出力しません。
+func WithKeepAllAttrs() Option {
+	return func(h *Handler) { h.keepAll = true }
+}
+
+// AttrExtractor は Handle 時に ctx から出力する属性を取り出す関数です。
+// 他のパッケージが context に保存した値 (トレース ID など) を、WithAttr せずにログへ出すために使います。
+// 出力する属性がなければ nil を返します。
+type AttrExtractor func(ctx context.Context) []slog.Attr
+
+// WithExtractor は extractors を登録するオプションです。
+// extractors が取り出した属性は、登録順に WithAttr による属性の後ろへトップレベル属性として出力します。
+// WithAttr で同じキーの属性を積んだ場合はそちらを優先し、WithoutAttr で隠したキーは出力しません。
+func WithExtractor(extractors ...AttrExtractor) Option {
+	return func(h *Handler) { h.extractors = append(h.extractors, extractors...) }
+}
+
 // NewHandler は Handler を作成して返します。
-func NewHandler(wrap slog.Handler) *Handler {
-	return &Handler{wrap: wrap}
+func NewHandler(wrap slog.Handler, opts ...Option) *Handler {
+	h := &Handler{wrap: wrap}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
-// Enabled はラップ先 Handler に委譲します。
+// Enabled は WithLevel で ctx にレベルが設定されていれば level がそれ以上のとき true を返し、
+// それ以外はラップ先 Handler に委譲します。
 func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	if lv, ok := levelFromContext(ctx); ok && level >= lv {
+		return true
+	}
 	return h.wrap.Enabled(ctx, level)
 }
 
 // Handle は WithAttr で ctx に積まれた属性をトップレベル属性として書き出した上で、
 // ラップ先 Handler にレコードの出力を委譲します。
+// Enabled と同じく、ctx のレベルかラップ先のレベルのどちらかで有効なレコードだけを出力します。
 func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	if !h.Enabled(ctx, record.Level) {
+		return nil
+	}
 	wrap := h.wrap
 
 	// ctx 由来の属性をトップレベル属性として wrap に積む
-	if attrs := attrsFromContext(ctx); len(attrs) > 0 {
+	if attrs := attrsFromContext(ctx, h.keepAll, h.extractors); len(attrs) > 0 {
 		wrap = wrap.WithAttrs(attrs)
 	}
 	// 蓄積していた group / group 内 attrs を再生
@@ -99,11 +284,10 @@ func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
 		return h
 	}
 	// group がまだ無ければ wrap に即時積む
+	h2 := *h
 	if len(h.groups) == 0 {
-		return &Handler{
-			wrap:   h.wrap.WithAttrs(attrs),
-			groups: h.groups,
-		}
+		h2.wrap = h.wrap.WithAttrs(attrs)
+		return &h2
 	}
 	// groups があれば最後の group の attrs に積む。
 	// slices.Clone は派生 Handler 間で backing array を共有しないため。
@@ -111,7 +295,8 @@ func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	last := newGroups[len(newGroups)-1]
 	last.attrs = append(slices.Clone(last.attrs), attrs...)
 	newGroups[len(newGroups)-1] = last
-	return &Handler{wrap: h.wrap, groups: newGroups}
+	h2.groups = newGroups
+	return &h2
 }
 
 // WithGroup は以後の属性が name 配下にネストされる新しい Handler を返します。
@@ -121,8 +306,7 @@ func (h *Handler) WithGroup(name string) slog.Handler {
 	if name == "" {
 		return h
 	}
-	return &Handler{
-		wrap:   h.wrap,
-		groups: append(slices.Clone(h.groups), groupState{name: name}),
-	}
+	h2 := *h
+	h2.groups = append(slices.Clone(h.groups), groupState{name: name})
+	return &h2
 }
@@ -6,7 +6,12 @@ import (
 	"encoding/json"
 	"io"
 	"log/slog"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"testing/slogtest"
 )
@@ -41,6 +46,54 @@ func TestHandlerComplies(t *testing.T) {
 	}
 }
 
+// TestHandlerCompliesWithCtxAttrs は ctx 属性 (WithAttr / WithGroupAttr) がある状態でも
+// slog.Handler 契約を満たし、全レコードのルートに ctx 属性が出ることを確認する。
+func TestHandlerCompliesWithCtxAttrs(t *testing.T) {
+	ctx := WithAttr(context.Background(), slog.String("trace_id", "abc123"))
+	ctx = WithGroupAttr(ctx, "req", slog.String("id", "r1"))
+	ctx = WithGroupAttr(ctx, "req", slog.String("method", "GET"))
+
+	h, buf := newTestHandler()
+	results := func() []map[string]any {
+		ms := parseLines(t, buf)
+		for _, m := range ms {
+			req, _ := m["req"].(map[string]any)
+			if m["trace_id"] != "abc123" || req["id"] != "r1" || req["method"] != "GET" || len(req) != 2 {
+				t.Errorf("ctx attrs not at root: %v", m)
+			}
+			// slogtest の検査に影響しないよう取り除く
+			delete(m, "trace_id")
+			delete(m, "req")
+		}
+		return ms
+	}
+	if err := slogtest.TestHandler(&ctxHandler{Handler: h, ctx: ctx}, results); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// ctxHandler は slogtest が渡す context の代わりに ctx で Handler を呼ぶ。
+type ctxHandler struct {
+	slog.Handler
+	ctx context.Context
+}
+
+func (h *ctxHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.Handler.Enabled(h.ctx, level)
+}
+
+func (h *ctxHandler) Handle(_ context.Context, record slog.Record) error {
+	return h.Handler.Handle(h.ctx, record)
+}
+
+func (h *ctxHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ctxHandler{Handler: h.Handler.WithAttrs(attrs), ctx: h.ctx}
+}
+
+func (h *ctxHandler) WithGroup(name string) slog.Handler {
+	return &ctxHandler{Handler: h.Handler.WithGroup(name), ctx: h.ctx}
+}
+
 // TestInjectsCtxAttrAsTopLevel は WithAttr した属性がトップレベルに出ることを確認する。
 func TestInjectsCtxAttrAsTopLevel(t *testing.T) {
 	h, buf := newTestHandler()
@@ -97,7 +150,7 @@ func TestCtxAttrChainInheritsAndOrders(t *testing.T) {
 	parent := WithAttr(context.Background(), slog.String("a", "1"))
 	child := WithAttr(parent, slog.String("b", "2"))
 
-	got := attrsFromContext(child)
+	got := attrsFromContext(child, false, nil)
 	if len(got) != 2 {
 		t.Fatalf("want 2 attrs, got %d: %v", len(got), got)
 	}
@@ -114,9 +167,9 @@ func TestWithAttrDoesNotMutateParentCtx(t *testing.T) {
 	childX := WithAttr(parent, slog.String("x", "X"))
 	childY := WithAttr(parent, slog.String("y", "Y"))
 
-	gotX := attrsFromContext(childX)
-	gotY := attrsFromContext(childY)
-	gotP := attrsFromContext(parent)
+	gotX := attrsFromContext(childX, false, nil)
+	gotY := attrsFromContext(childY, false, nil)
+	gotP := attrsFromContext(parent, false, nil)
 
 	if len(gotP) != 1 || gotP[0].Key != "a" {
 		t.Errorf("parent should only have a: %v", gotP)
@@ -217,7 +270,7 @@ func TestWithAttrConcurrent(t *testing.T) {
 		go func(i int) {
 			defer wg.Done()
 			ctx := WithAttr(parent, slog.Int("i", i))
-			attrs := attrsFromContext(ctx)
+			attrs := attrsFromContext(ctx, false, nil)
 			if len(attrs) != 2 {
 				t.Errorf("goroutine %d: want 2 attrs, got %d: %v", i, len(attrs), attrs)
 				return
@@ -233,6 +286,421 @@ func TestWithAttrConcurrent(t *testing.T) {
 	wg.Wait()
 }
 
+// TestCtxAttrLastWriterWins は同じキーを複数回 WithAttr した場合に、
+// 最後に積んだ値だけが 1 回出力されることを確認する。
+func TestCtxAttrLastWriterWins(t *testing.T) {
+	h, buf := newTestHandler()
+	logger := slog.New(h)
+
+	ctx := WithAttr(context.Background(), slog.String("user_id", "u1"))
+	ctx = WithAttr(ctx, slog.String("tenant", "t1"))
+	ctx = WithAttr(ctx, slog.String("user_id", "u2"))
+	logger.InfoContext(ctx, "hello")
+
+	if got := bytes.Count(buf.Bytes(), []byte(`"user_id"`)); got != 1 {
+		t.Errorf("user_id appears %d times: %s", got, buf)
+	}
+	ms := parseLines(t, buf)
+	if v := ms[0]["user_id"]; v != "u2" {
+		t.Errorf("user_id = %v, want u2", v)
+	}
+	if v := ms[0]["tenant"]; v != "t1" {
+		t.Errorf("tenant = %v, want t1", v)
+	}
+
+	got := attrsFromContext(ctx, false, nil)
+	if len(got) != 2 || got[0].Key != "tenant" || got[1].Key != "user_id" {
+		t.Errorf("want [tenant, user_id], got %v", got)
+	}
+}
+
+// TestKeepAllAttrs は WithKeepAllAttrs で重複キーもすべて出力されることを確認する。
+func TestKeepAllAttrs(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(NewHandler(slog.NewJSONHandler(buf, nil), WithKeepAllAttrs()))
+	// WithGroup で派生した Handler にもオプションが引き継がれること
+	logger = logger.WithGroup("g").With("k", "v")
+
+	ctx := WithAttr(context.Background(), slog.String("user_id", "u1"))
+	ctx = WithAttr(ctx, slog.String("user_id", "u2"))
+	logger.InfoContext(ctx, "hello")
+
+	if got := bytes.Count(buf.Bytes(), []byte(`"user_id"`)); got != 2 {
+		t.Errorf("user_id appears %d times, want 2: %s", got, buf)
+	}
+}
+
+// TestWithoutAttr は WithoutAttr が祖先の属性をその子孫 ctx でだけ隠すことを確認する。
+func TestWithoutAttr(t *testing.T) {
+	keys := func(attrs []slog.Attr) []string {
+		var ks []string
+		for _, a := range attrs {
+			ks = append(ks, a.Key+"="+a.Value.String())
+		}
+		return ks
+	}
+
+	parent := WithAttr(context.Background(), slog.String("token", "secret"))
+	parent = WithAttr(parent, slog.String("a", "1"))
+	masked := WithoutAttr(parent, "token")
+	readded := WithAttr(masked, slog.String("token", "***"))
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		keepAll bool
+		want    []string
+	}{
+		{name: "parent", ctx: parent, want: []string{"token=secret", "a=1"}},
+		{name: "masked", ctx: masked, want: []string{"a=1"}},
+		{name: "masked child", ctx: WithAttr(masked, slog.String("b", "2")), want: []string{"a=1", "b=2"}},
+		{name: "readded", ctx: readded, want: []string{"a=1", "token=***"}},
+		{name: "masked keepAll", ctx: WithAttr(masked, slog.String("a", "3")), keepAll: true, want: []string{"a=1", "a=3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := keys(attrsFromContext(tt.ctx, tt.keepAll, nil)); !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestAttrsFromContextAllocs は重複排除してもアロケーションが返り値の 1 回で済むことを確認する。
+func TestAttrsFromContextAllocs(t *testing.T) {
+	ctx := context.Background()
+	for i := range 8 {
+		ctx = WithAttr(ctx, slog.Int("k"+strconv.Itoa(i%4), i))
+	}
+	allocs := testing.AllocsPerRun(100, func() { attrsFromContext(ctx, false, nil) })
+	if allocs != 1 {
+		t.Errorf("allocs = %v, want 1", allocs)
+	}
+}
+
+// TestWithLevel は WithLevel した ctx でだけ DEBUG ログが出力され、
+// ラップ先のレベルの方が低い場合はそちらが効くことを確認する。
+func TestWithLevel(t *testing.T) {
+	tests := []struct {
+		name      string
+		wrapLevel slog.Level
+		ctxLevel  *slog.Level
+		logLevel  slog.Level
+		want      bool
+	}{
+		{name: "no ctx level, debug filtered", wrapLevel: slog.LevelInfo, logLevel: slog.LevelDebug, want: false},
+		{name: "no ctx level, info", wrapLevel: slog.LevelInfo, logLevel: slog.LevelInfo, want: true},
+		{name: "ctx debug enables debug", wrapLevel: slog.LevelInfo, ctxLevel: new(slog.LevelDebug), logLevel: slog.LevelDebug, want: true},
+		{name: "ctx warn does not raise wrap level", wrapLevel: slog.LevelInfo, ctxLevel: new(slog.LevelWarn), logLevel: slog.LevelInfo, want: true},
+		{name: "ctx info, debug filtered", wrapLevel: slog.LevelInfo, ctxLevel: new(slog.LevelInfo), logLevel: slog.LevelDebug, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			logger := slog.New(NewHandler(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: tt.wrapLevel})))
+			ctx := context.Background()
+			if tt.ctxLevel != nil {
+				ctx = WithLevel(ctx, *tt.ctxLevel)
+			}
+			logger.Log(ctx, tt.logLevel, "hello")
+			if got := buf.Len() > 0; got != tt.want {
+				t.Errorf("logged = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestWithLevelConcurrent は DEBUG を有効にしたリクエストと並行して処理される
+// 他のリクエストが INFO のままであることを確認する。go test -race で実行すること。
+func TestWithLevelConcurrent(t *testing.T) {
+	// JSONHandler は派生 Handler 間で共有する mutex で書き込みを直列化するので、bytes.Buffer のままでよい
+	h, buf := newTestHandler()
+	logger := slog.New(h)
+
+	var wg sync.WaitGroup
+	for i := range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := WithAttr(context.Background(), slog.Int("req", i))
+			if i%10 == 0 {
+				ctx = WithLevel(ctx, slog.LevelDebug)
+			}
+			logger.DebugContext(ctx, "debug")
+			logger.InfoContext(ctx, "info")
+		}()
+	}
+	wg.Wait()
+
+	var debugs, infos int
+	for _, m := range parseLines(t, buf) {
+		req := int(m["req"].(float64))
+		switch m["level"] {
+		case "DEBUG":
+			debugs++
+			if req%10 != 0 {
+				t.Errorf("req %d logged DEBUG without WithLevel", req)
+			}
+		case "INFO":
+			infos++
+		}
+	}
+	if debugs != 10 || infos != 100 {
+		t.Errorf("debugs = %d, infos = %d, want 10, 100", debugs, infos)
+	}
+}
+
+// TestWithLazyAttr は WithLazyAttr の値が出力時に 1 度だけ評価され、
+// 出力されない場合は評価されないことを確認する。
+func TestWithLazyAttr(t *testing.T) {
+	h, buf := newTestHandler()
+	logger := slog.New(h)
+
+	var calls atomic.Int32
+	ctx := WithLazyAttr(context.Background(), "principal", func() slog.Value {
+		calls.Add(1)
+		return slog.StringValue("alice")
+	})
+
+	logger.DebugContext(ctx, "filtered")
+	if n := calls.Load(); n != 0 {
+		t.Fatalf("evaluated %d times for a filtered record", n)
+	}
+
+	child := WithAttr(ctx, slog.String("k", "v"))
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.InfoContext(child, "hello")
+		}()
+	}
+	wg.Wait()
+	logger.InfoContext(ctx, "parent")
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("evaluated %d times, want 1", n)
+	}
+	for _, m := range parseLines(t, buf) {
+		if v := m["principal"]; v != "alice" {
+			t.Errorf("principal = %v, want alice: %v", v, m)
+		}
+	}
+}
+
+// TestWithLazyAttrOverridden は上書き・マスクされた遅延属性が評価されないことを確認する。
+func TestWithLazyAttrOverridden(t *testing.T) {
+	h, buf := newTestHandler()
+	logger := slog.New(h)
+
+	called := false
+	ctx := WithLazyAttr(context.Background(), "stats", func() slog.Value {
+		called = true
+		return slog.StringValue("expensive")
+	})
+	logger.InfoContext(WithAttr(ctx, slog.String("stats", "cheap")), "overridden")
+	logger.InfoContext(WithoutAttr(ctx, "stats"), "masked")
+
+	if called {
+		t.Error("evaluated the overridden attr")
+	}
+	ms := parseLines(t, buf)
+	if v := ms[0]["stats"]; v != "cheap" {
+		t.Errorf("stats = %v, want cheap", v)
+	}
+	if _, ok := ms[1]["stats"]; ok {
+		t.Errorf("stats not masked: %v", ms[1])
+	}
+}
+
+// TestWithGroupAttr は WithGroupAttr の属性が logger の WithGroup に関わらずルートの group に
+// まとめて出力されることを確認する。
+func TestWithGroupAttr(t *testing.T) {
+	h, buf := newTestHandler()
+	logger := slog.New(h).WithGroup("g")
+
+	ctx := WithGroupAttr(context.Background(), "req", slog.String("id", "r1"), slog.String("method", "GET"))
+	ctx = WithAttr(ctx, slog.String("trace_id", "abc123"))
+	ctx = WithGroupAttr(ctx, "req", slog.String("method", "POST"), slog.String("path", "/users"))
+	ctx = WithGroupAttr(ctx, "auth", slog.String("user", "alice"))
+	logger.InfoContext(ctx, "hello", "k", "v")
+
+	ms := parseLines(t, buf)
+	if len(ms) != 1 {
+		t.Fatalf("want 1 line, got %d", len(ms))
+	}
+	m := ms[0]
+	req, ok := m["req"].(map[string]any)
+	if !ok {
+		t.Fatalf("group req not at root: %v", m)
+	}
+	want := map[string]any{"id": "r1", "method": "POST", "path": "/users"}
+	if len(req) != len(want) {
+		t.Errorf("req = %v, want %v", req, want)
+	}
+	for k, v := range want {
+		if req[k] != v {
+			t.Errorf("req.%s = %v, want %v", k, req[k], v)
+		}
+	}
+	if auth, _ := m["auth"].(map[string]any); auth["user"] != "alice" {
+		t.Errorf("auth.user not at root: %v", m)
+	}
+	if m["trace_id"] != "abc123" {
+		t.Errorf("trace_id not at root: %v", m)
+	}
+	if g, _ := m["g"].(map[string]any); g["k"] != "v" || len(g) != 1 {
+		t.Errorf("g = %v, want {k: v}", m["g"])
+	}
+	if got := bytes.Count(buf.Bytes(), []byte(`"req"`)); got != 1 {
+		t.Errorf("req appears %d times: %s", got, buf)
+	}
+
+	// 出力順: トップレベル属性 → 最初に積まれた順の group
+	got := attrsFromContext(ctx, false, nil)
+	var keys []string
+	for _, a := range got {
+		keys = append(keys, a.Key)
+	}
+	if want := []string{"trace_id", "req", "auth"}; !slices.Equal(keys, want) {
+		t.Errorf("keys = %v, want %v", keys, want)
+	}
+}
+
+// TestWithGroupAttrMaskAndKeepAll は WithoutAttr で group ごと隠せること、
+// WithKeepAllAttrs では group 内の重複キーも出力されることを確認する。
+func TestWithGroupAttrMaskAndKeepAll(t *testing.T) {
+	ctx := WithGroupAttr(context.Background(), "req", slog.String("id", "r1"))
+	ctx = WithGroupAttr(ctx, "req", slog.String("id", "r2"))
+
+	if got := attrsFromContext(WithoutAttr(ctx, "req"), false, nil); len(got) != 0 {
+		t.Errorf("req not masked: %v", got)
+	}
+	got := attrsFromContext(ctx, true, nil)
+	if len(got) != 1 || got[0].Key != "req" || len(got[0].Value.Group()) != 2 {
+		t.Errorf("want req with 2 ids, got %v", got)
+	}
+	// group 名が空ならトップレベル属性として積む
+	if got := attrsFromContext(WithGroupAttr(context.Background(), "", slog.String("a", "1")), false, nil); len(got) != 1 || got[0].Key != "a" {
+		t.Errorf("want [a], got %v", got)
+	}
+}
+
+// TestWithExtractor は複数の AttrExtractor が登録順に ctx 属性の後ろへ出力され、
+// WithGroup / WithAttrs で派生した Handler にも引き継がれることを確認する。
+func TestWithExtractor(t *testing.T) {
+	h, buf := newTestHandler()
+	type tenantKey struct{}
+	tenant := func(ctx context.Context) []slog.Attr {
+		if v, ok := ctx.Value(tenantKey{}).(string); ok {
+			return []slog.Attr{slog.String("tenant", v)}
+		}
+		return nil
+	}
+	static := func(context.Context) []slog.Attr { return []slog.Attr{slog.String("app", "demo")} }
+	logger := slog.New(NewHandler(h.wrap, WithExtractor(tenant), WithExtractor(static))).WithGroup("g").With("k", "v")
+
+	ctx := WithAttr(context.WithValue(context.Background(), tenantKey{}, "acme"), slog.String("user", "alice"))
+	logger.InfoContext(ctx, "hello")
+	logger.InfoContext(context.Background(), "no tenant")
+
+	ms := parseLines(t, buf)
+	if ms[0]["tenant"] != "acme" || ms[0]["app"] != "demo" || ms[0]["user"] != "alice" {
+		t.Errorf("extracted attrs not at root: %v", ms[0])
+	}
+	if _, ok := ms[1]["tenant"]; ok || ms[1]["app"] != "demo" {
+		t.Errorf("unexpected attrs: %v", ms[1])
+	}
+	line := buf.String()
+	if i, j, k := strings.Index(line, `"user"`), strings.Index(line, `"tenant"`), strings.Index(line, `"app"`); !(i < j && j < k) {
+		t.Errorf("want order user, tenant, app: %s", line)
+	}
+}
+
+// TestWithExtractorDedup は extractor が取り出した属性も重複排除され (WithAttr した値が優先)、
+// WithoutAttr で隠せることを確認する。WithKeepAllAttrs ではどちらも出力される。
+func TestWithExtractorDedup(t *testing.T) {
+	traceID := func(context.Context) []slog.Attr { return []slog.Attr{slog.String("trace_id", "extracted")} }
+	other := func(context.Context) []slog.Attr {
+		return []slog.Attr{slog.String("trace_id", "other"), slog.String("span_id", "s1")}
+	}
+	explicit := WithAttr(context.Background(), slog.String("trace_id", "explicit"))
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		keepAll bool
+		want    []string
+	}{
+		{name: "extracted only", ctx: context.Background(), want: []string{"trace_id=extracted", "span_id=s1"}},
+		{name: "WithAttr wins", ctx: explicit, want: []string{"trace_id=explicit", "span_id=s1"}},
+		{name: "masked", ctx: WithoutAttr(explicit, "trace_id"), want: []string{"span_id=s1"}},
+		{name: "keep all", ctx: explicit, keepAll: true, want: []string{"trace_id=explicit", "trace_id=extracted", "trace_id=other", "span_id=s1"}},
+		{name: "masked keep all", ctx: WithoutAttr(explicit, "trace_id"), keepAll: true, want: []string{"span_id=s1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, a := range attrsFromContext(tt.ctx, tt.keepAll, []AttrExtractor{traceID, other}) {
+				got = append(got, a.Key+"="+a.Value.String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Handler 経由でも trace_id は 1 回だけ出力される
+	h, buf := newTestHandler()
+	logger := slog.New(NewHandler(h.wrap, WithExtractor(traceID)))
+	logger.InfoContext(explicit, "hello")
+	if got := bytes.Count(buf.Bytes(), []byte(`"trace_id"`)); got != 1 {
+		t.Errorf("trace_id appears %d times: %s", got, buf)
+	}
+	if m := parseLines(t, buf)[0]; m["trace_id"] != "explicit" {
+		t.Errorf("trace_id = %v, want explicit", m["trace_id"])
+	}
+}
+
+// TestWithGroupAttrKeyCollision は group 名と同じキーのトップレベル属性・extractor 属性が
+// 後から積んだ方だけ出力され、"req" キーが重複しないことを確認する。
+func TestWithGroupAttrKeyCollision(t *testing.T) {
+	legacy := WithAttr(context.Background(), slog.String("req", "legacy"))
+	grouped := WithGroupAttr(legacy, "req", slog.String("id", "r1"))
+	overridden := WithAttr(grouped, slog.String("req", "flat"))
+	extractReq := func(context.Context) []slog.Attr { return []slog.Attr{slog.String("req", "extracted")} }
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want any
+	}{
+		{name: "group after WithAttr", ctx: grouped, want: map[string]any{"id": "r1"}},
+		{name: "WithAttr after group", ctx: overridden, want: "flat"},
+		{name: "extractor loses to group", ctx: WithGroupAttr(context.Background(), "req", slog.String("id", "r1")), want: map[string]any{"id": "r1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, buf := newTestHandler()
+			logger := slog.New(NewHandler(h.wrap, WithExtractor(extractReq)))
+			logger.InfoContext(tt.ctx, "hello")
+
+			if got := bytes.Count(buf.Bytes(), []byte(`"req"`)); got != 1 {
+				t.Errorf("req appears %d times: %s", got, buf)
+			}
+			if m := parseLines(t, buf)[0]; !reflect.DeepEqual(m["req"], tt.want) {
+				t.Errorf("req = %v, want %v", m["req"], tt.want)
+			}
+		})
+	}
+
+	// keepAll ではどちらも出力される
+	if got := attrsFromContext(grouped, true, nil); len(got) != 2 {
+		t.Errorf("want 2 attrs with keepAll, got %v", got)
+	}
+}
+
 // BenchmarkHandlers は素の JSONHandler と slogctx.Handler のスループット比較。
 // io.Discard に書き出して serialize 以外のオーバーヘッドを見やすくしている。
 // 実行: go test -bench=. -benchmem ./src/slog/ctx/
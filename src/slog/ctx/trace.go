@@ -0,0 +1,136 @@
+package slogctx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// ErrInvalidTraceparent は traceparent ヘッダーの形式が不正な場合に返されます。
+var ErrInvalidTraceparent = errors.New("slogctx: invalid traceparent")
+
+// SpanContext は W3C Trace Context (https://www.w3.org/TR/trace-context/) のトレース ID とスパン ID です。
+// OpenTelemetry に依存せずに trace_id / span_id をログへ出すための最小限の表現です。
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	// ParentSpanID は traceparent ヘッダーで受け取った呼び出し元のスパン ID です。
+	ParentSpanID [8]byte
+	Flags        byte
+}
+
+// IsValid は TraceID と SpanID がどちらもゼロでないかを返します。
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// Sampled は sampled フラグが立っているかを返します。
+func (sc SpanContext) Sampled() bool {
+	return sc.Flags&0x01 != 0
+}
+
+// Traceparent は sc を下流へ伝播するための traceparent ヘッダーの値を返します。
+func (sc SpanContext) Traceparent() string {
+	b := make([]byte, 0, 55)
+	b = append(b, "00-"...)
+	b = hex.AppendEncode(b, sc.TraceID[:])
+	b = append(b, '-')
+	b = hex.AppendEncode(b, sc.SpanID[:])
+	b = append(b, '-')
+	b = hex.AppendEncode(b, []byte{sc.Flags})
+	return string(b)
+}
+
+// ParseTraceparent は traceparent ヘッダーの値を解析します。
+// 返す SpanContext の SpanID はヘッダーの parent-id で、ParentSpanID は空です。
+// 将来のバージョン (00 と ff 以外) は、仕様に従い先頭の 55 文字だけを解析します。
+func ParseTraceparent(s string) (SpanContext, error) {
+	var sc SpanContext
+	// version "-" trace-id "-" parent-id "-" trace-flags
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return sc, ErrInvalidTraceparent
+	}
+	version, ok := decodeLowerHex(s[0:2])
+	if !ok || version[0] == 0xff {
+		return sc, ErrInvalidTraceparent
+	}
+	if version[0] == 0 && len(s) != 55 {
+		return sc, ErrInvalidTraceparent
+	}
+	if version[0] != 0 && len(s) > 55 && s[55] != '-' {
+		return sc, ErrInvalidTraceparent
+	}
+	traceID, ok1 := decodeLowerHex(s[3:35])
+	spanID, ok2 := decodeLowerHex(s[36:52])
+	flags, ok3 := decodeLowerHex(s[53:55])
+	if !ok1 || !ok2 || !ok3 {
+		return sc, ErrInvalidTraceparent
+	}
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Flags = flags[0]
+	if !sc.IsValid() {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	return sc, nil
+}
+
+// decodeLowerHex は仕様で大文字が許されないため、小文字の 16 進数だけを受け付けます。
+func decodeLowerHex(s string) ([]byte, bool) {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return nil, false
+		}
+	}
+	b, err := hex.DecodeString(s)
+	return b, err == nil
+}
+
+type spanContextKeyType struct{}
+
+var spanContextKey = spanContextKeyType{}
+
+// ContextWithSpanContext は sc を持つ新しい context を返します。
+func ContextWithSpanContext(parent context.Context, sc SpanContext) context.Context {
+	return context.WithValue(parent, spanContextKey, sc)
+}
+
+// SpanContextFromContext は ctx の SpanContext を返します。
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanContextKey).(SpanContext)
+	return sc, ok
+}
+
+// TraceExtractor は ctx の SpanContext を trace_id / span_id 属性として取り出す AttrExtractor です。
+//
+//	slog.New(slogctx.NewHandler(h, slogctx.WithExtractor(slogctx.TraceExtractor)))
+func TraceExtractor(ctx context.Context) []slog.Attr {
+	sc, ok := SpanContextFromContext(ctx)
+	if !ok || !sc.IsValid() {
+		return nil
+	}
+	return []slog.Attr{
+		slog.String("trace_id", hex.EncodeToString(sc.TraceID[:])),
+		slog.String("span_id", hex.EncodeToString(sc.SpanID[:])),
+	}
+}
+
+// TraceparentMiddleware はリクエストごとの SpanContext を context に保存する HTTP ミドルウェアです。
+// traceparent ヘッダーが正しければそのトレース ID を引き継ぎ、ヘッダーの parent-id を ParentSpanID とします。
+// ヘッダーがないか不正であれば新しいトレース ID を生成します。どちらの場合もスパン ID は新しく生成します。
+func TraceparentMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sc, err := ParseTraceparent(r.Header.Get("traceparent"))
+		if err == nil {
+			sc.ParentSpanID = sc.SpanID
+		} else {
+			sc = SpanContext{}
+			rand.Read(sc.TraceID[:])
+		}
+		rand.Read(sc.SpanID[:])
+		next.ServeHTTP(w, r.WithContext(ContextWithSpanContext(r.Context(), sc)))
+	})
+}
@@ -0,0 +1,106 @@
+package slogctx
+
+import (
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestParseTraceparent は W3C Trace Context 仕様の traceparent の解析を確認する。
+func TestParseTraceparent(t *testing.T) {
+	const valid = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	tests := []struct {
+		name    string
+		in      string
+		wantErr bool
+	}{
+		{name: "valid", in: valid},
+		{name: "future version with extra fields", in: "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-what-the-future-will-be-like"},
+		{name: "empty", in: "", wantErr: true},
+		{name: "version ff", in: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", wantErr: true},
+		{name: "version 00 with extra fields", in: valid + "-extra", wantErr: true},
+		{name: "upper case", in: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", wantErr: true},
+		{name: "zero trace id", in: "00-00000000000000000000000000000000-00f067aa0ba902b7-01", wantErr: true},
+		{name: "zero span id", in: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", wantErr: true},
+		{name: "bad delimiter", in: "00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, err := ParseTraceparent(tt.in)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidTraceparent) {
+					t.Errorf("err = %v, want ErrInvalidTraceparent", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(sc.TraceID[:]); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+				t.Errorf("trace id = %v", got)
+			}
+			if got := hex.EncodeToString(sc.SpanID[:]); got != "00f067aa0ba902b7" {
+				t.Errorf("span id = %v", got)
+			}
+			if !sc.Sampled() {
+				t.Error("want sampled")
+			}
+		})
+	}
+
+	sc, _ := ParseTraceparent(valid)
+	if got := sc.Traceparent(); got != valid {
+		t.Errorf("Traceparent() = %v, want %v", got, valid)
+	}
+}
+
+// TestTraceparentMiddleware はミドルウェアがトレース ID を引き継ぎ、新しいスパン ID を生成して
+// TraceExtractor 経由で全ログに trace_id / span_id が出ることを確認する。
+func TestTraceparentMiddleware(t *testing.T) {
+	h, buf := newTestHandler()
+	logger := slog.New(NewHandler(h.wrap, WithExtractor(TraceExtractor))).WithGroup("g")
+
+	var got SpanContext
+	handler := TraceparentMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = SpanContextFromContext(r.Context())
+		logger.InfoContext(r.Context(), "hello")
+	}))
+
+	t.Run("propagated", func(t *testing.T) {
+		buf.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		if got := hex.EncodeToString(got.TraceID[:]); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("trace id = %v", got)
+		}
+		if got := hex.EncodeToString(got.ParentSpanID[:]); got != "00f067aa0ba902b7" {
+			t.Errorf("parent span id = %v", got)
+		}
+		if got.SpanID == got.ParentSpanID || !got.Sampled() {
+			t.Errorf("want a new sampled span: %+v", got)
+		}
+		m := parseLines(t, buf)[0]
+		if m["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" || m["span_id"] != hex.EncodeToString(got.SpanID[:]) {
+			t.Errorf("trace_id / span_id not at root: %v", m)
+		}
+	})
+
+	t.Run("new trace", func(t *testing.T) {
+		buf.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("traceparent", "invalid")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		if !got.IsValid() || got.ParentSpanID != [8]byte{} {
+			t.Errorf("want a new root span: %+v", got)
+		}
+		if m := parseLines(t, buf)[0]; m["trace_id"] != hex.EncodeToString(got.TraceID[:]) {
+			t.Errorf("trace_id = %v", m["trace_id"])
+		}
+	})
+}